@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const cefVersion = 0
+
+// cefKeys - keys of extensions set from Event fields
+var cefKeys = []string{"rt", "suser", "act", "cs1Label", "cs1", "outcome", "src"}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+// CEF - ArcSight Common Event Format encoder:
+// "CEF:0|Vendor|Product|Version|ID|Name|Severity|Extension"
+type CEF struct {
+	Vendor  string
+	Product string
+	Version string
+}
+
+// Encode - implements Encoder
+func (f CEF) Encode(e *Event) string {
+	b := new(strings.Builder)
+	b.WriteString("CEF:")
+	b.WriteString(strconv.Itoa(cefVersion))
+	for _, h := range []string{f.Vendor, f.Product, f.Version, e.ID, e.Name} {
+		b.WriteByte('|')
+		b.WriteString(cefHeaderEscaper.Replace(h))
+	}
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(severity(e.Severity, 0)))
+	b.WriteByte('|')
+
+	ext := [][2]string{
+		{"rt", strconv.FormatInt(e.Time.UnixNano()/1e6, 10)},
+		{"suser", e.Actor},
+		{"act", e.Action},
+	}
+	if e.Target != "" {
+		ext = append(ext, [2]string{"cs1Label", "target"}, [2]string{"cs1", e.Target})
+	}
+	ext = append(ext, [2]string{"outcome", string(e.Outcome)}, [2]string{"src", e.SourceIP})
+	ext = append(ext, sortedExtensions(e.Extensions, ' ', cefKeys)...)
+
+	first := true
+	for _, kv := range ext {
+		if kv[1] == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(kv[0])
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(kv[1]))
+	}
+	return b.String()
+}
+
+// extensionPrefix - prefix of extension keys which collide with built-in or other extension keys
+const extensionPrefix = "ext_"
+
+// sortedExtensions - returns extensions as key-value pairs in stable order, with keys made valid
+// by extensionKey. Keys colliding with reserved (built-in) keys or with each other are prefixed with
+// extensionPrefix, so every key is sent once. Empty keys are skipped
+func sortedExtensions(ext map[string]string, delim rune, reserved []string) [][2]string {
+	keys := make([]string, 0, len(ext))
+	for k := range ext {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	used := make(map[string]bool, len(reserved)+len(keys))
+	for _, k := range reserved {
+		used[k] = true
+	}
+	res := make([][2]string, 0, len(keys))
+	for _, k := range keys {
+		if k == "" {
+			continue
+		}
+		key := extensionKey(k, delim)
+		for used[key] {
+			key = extensionPrefix + key
+		}
+		used[key] = true
+		res = append(res, [2]string{key, ext[k]})
+	}
+	return res
+}
+
+// extensionKey - returns k with characters other than ASCII letters, digits, '_', '.' and '-'
+// (and attributes delimiter delim) replaced, so key cannot break key=value pairs
+func extensionKey(k string, delim rune) string {
+	repl := '_'
+	if delim == repl {
+		repl = '-'
+	}
+	return strings.Map(func(r rune) rune {
+		if r == delim || !isKeyChar(r) {
+			return repl
+		}
+		return r
+	}, k)
+}
+
+func isKeyChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-'
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	logger "slogger"
+)
+
+// Outcome - result of audited action
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeUnknown Outcome = "unknown"
+)
+
+// Severity levels of audit event (CEF scale, 0-10)
+const (
+	SeverityLow      = 3
+	SeverityMedium   = 6
+	SeverityHigh     = 8
+	SeverityVeryHigh = 10
+)
+
+// Event - security audit event
+type Event struct {
+	// ID - event class id (CEF Signature ID, LEEF Event ID)
+	ID string
+	// Name - human readable event description
+	Name string
+	// Time - event time, time of Audit call if zero
+	Time time.Time
+	// Severity - importance of event, 0 (lowest) - 10 (highest)
+	Severity int
+
+	Actor    string
+	Action   string
+	Target   string
+	Outcome  Outcome
+	SourceIP string
+
+	// Extensions - additional key-value pairs. Characters of keys other than ASCII letters, digits,
+	// '_', '.' and '-' (and LEEF delimiter) are replaced with '_'. Keys colliding with keys of
+	// the fields above (e.g. "src") or with each other after replacement are prefixed with "ext_"
+	Extensions map[string]string
+}
+
+// Encoder - encodes audit event to syslog message
+type Encoder interface {
+	Encode(e *Event) string
+}
+
+// Logger - sends audit events with slogger.Logger
+type Logger struct {
+	l   logger.Logger
+	enc Encoder
+}
+
+// New - creates audit logger on top of l, events are encoded with enc (CEF or LEEF)
+func New(l logger.Logger, enc Encoder) *Logger {
+	return &Logger{
+		l:   l,
+		enc: enc,
+	}
+}
+
+// Audit - encodes event and sends it with syslog severity matching event severity
+func (a *Logger) Audit(ctx context.Context, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	m := a.enc.Encode(&e)
+
+	switch {
+	case e.Severity > SeverityHigh:
+		a.l.Crit(ctx, m)
+	case e.Severity > SeverityMedium:
+		a.l.Err(ctx, m)
+	case e.Severity > SeverityLow:
+		a.l.Warning(ctx, m)
+	default:
+		a.l.Notice(ctx, m)
+	}
+}
+
+// severity - returns severity clamped to [min, 10]
+func severity(s, min int) int {
+	if s < min {
+		return min
+	}
+	if s > SeverityVeryHigh {
+		return SeverityVeryHigh
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	level, msg string
+}
+
+func (l *testLogger) Close() error                          { return nil }
+func (l *testLogger) Alert(ctx context.Context, m string)   { l.level, l.msg = "alert", m }
+func (l *testLogger) Crit(ctx context.Context, m string)    { l.level, l.msg = "crit", m }
+func (l *testLogger) Debug(ctx context.Context, m string)   { l.level, l.msg = "debug", m }
+func (l *testLogger) Emerg(ctx context.Context, m string)   { l.level, l.msg = "emerg", m }
+func (l *testLogger) Err(ctx context.Context, m string)     { l.level, l.msg = "err", m }
+func (l *testLogger) Info(ctx context.Context, m string)    { l.level, l.msg = "info", m }
+func (l *testLogger) Notice(ctx context.Context, m string)  { l.level, l.msg = "notice", m }
+func (l *testLogger) Warning(ctx context.Context, m string) { l.level, l.msg = "warning", m }
+
+func testEvent() Event {
+	return Event{
+		ID:       "login|1",
+		Name:     "User login",
+		Time:     time.Unix(1564815489, 0),
+		Severity: SeverityHigh,
+		Actor:    "john=doe",
+		Action:   "login",
+		Target:   "db\\main",
+		Outcome:  OutcomeFailure,
+		SourceIP: "10.0.0.1",
+		Extensions: map[string]string{
+			"msg": "bad\npassword",
+		},
+	}
+}
+
+func TestCEF(t *testing.T) {
+	e := testEvent()
+	expect := `CEF:0|Acme|Shop|1.0|login\|1|User login|8|rt=1564815489000 suser=john\=doe act=login ` +
+		`cs1Label=target cs1=db\\main outcome=failure src=10.0.0.1 msg=bad\npassword`
+	if m := (CEF{Vendor: "Acme", Product: "Shop", Version: "1.0"}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+}
+
+func TestLEEF(t *testing.T) {
+	e := testEvent()
+	expect := "LEEF:2.0|Acme|Shop|1.0|login\\|1|x09|devTime=1564815489000\tsev=8\tname=User login\t" +
+		"usrName=john=doe\taction=login\ttarget=db\\\\main\toutcome=failure\tsrc=10.0.0.1\tmsg=bad\\npassword"
+	if m := (LEEF{Vendor: "Acme", Product: "Shop", Version: "1.0"}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+
+	e = Event{Time: e.Time, Name: "a^b"}
+	expect = "LEEF:2.0|||||x5e|devTime=1564815489000^sev=1^name=a\\^b"
+	if m := (LEEF{Delimiter: '^'}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+}
+
+func TestExtensions_Collision(t *testing.T) {
+	e := Event{
+		Time:     time.Unix(1564815489, 0),
+		SourceIP: "10.0.0.1",
+		Extensions: map[string]string{
+			"src":     "forged",
+			"ext_src": "other",
+			"a b":     "1",
+			"a_b":     "2",
+		},
+	}
+	expect := `CEF:0||||||0|rt=1564815489000 src=10.0.0.1 a_b=1 ext_a_b=2 ext_src=other ext_ext_src=forged`
+	if m := (CEF{}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+
+	e.Extensions = map[string]string{"devTime": "0", "usrName": "forged"}
+	expect = "LEEF:2.0|||||x09|devTime=1564815489000\tsev=1\tsrc=10.0.0.1\text_devTime=0\text_usrName=forged"
+	if m := (LEEF{}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+}
+
+func TestLogger_Audit(t *testing.T) {
+	l := &testLogger{}
+	a := New(l, CEF{Vendor: "Acme", Product: "Shop", Version: "1.0"})
+
+	for _, tc := range []struct {
+		severity int
+		level    string
+	}{
+		{0, "notice"}, {SeverityLow, "notice"}, {SeverityMedium, "warning"},
+		{SeverityHigh, "err"}, {SeverityVeryHigh, "crit"},
+	} {
+		a.Audit(context.Background(), Event{Severity: tc.severity})
+		if l.level != tc.level {
+			t.Errorf("severity %d: expect level %s, got %s", tc.severity, tc.level, l.level)
+		}
+		if l.msg == "" {
+			t.Errorf("severity %d: expect message, got empty", tc.severity)
+		}
+	}
+}
+
+func TestExtensionKeys(t *testing.T) {
+	e := Event{Time: time.Unix(1564815489, 0), Extensions: map[string]string{
+		"bad key=x": "v",
+		"ok.key":    "w",
+		"":          "skipped",
+	}}
+	expect := "CEF:0||||||0|rt=1564815489000 bad_key_x=v ok.key=w"
+	if m := (CEF{}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+	e.Extensions = map[string]string{"a^b": "v"}
+	expect = "LEEF:2.0|||||x5e|devTime=1564815489000^sev=1^a_b=v"
+	if m := (LEEF{Delimiter: '^'}).Encode(&e); m != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, m)
+	}
+	if k := extensionKey("a_b", '_'); k != "a-b" {
+		t.Errorf("expect delimiter replaced in key, got: %s", k)
+	}
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const leefVersion = "2.0"
+
+var leefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+
+// LEEF - QRadar Log Event Extended Format 2.0 encoder:
+// "LEEF:2.0|Vendor|Product|Version|ID|xHH|key=value<delimiter>key=value...",
+// where xHH is hex code of delimiter. devTime is sent as epoch milliseconds
+type LEEF struct {
+	Vendor  string
+	Product string
+	Version string
+	// Delimiter - attributes delimiter, tab if zero
+	Delimiter rune
+}
+
+// Encode - implements Encoder
+func (f LEEF) Encode(e *Event) string {
+	delim := f.Delimiter
+	if delim == 0 {
+		delim = '\t'
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, string(delim), `\`+string(delim),
+		"\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+
+	b := new(strings.Builder)
+	b.WriteString("LEEF:")
+	b.WriteString(leefVersion)
+	for _, h := range []string{f.Vendor, f.Product, f.Version, e.ID} {
+		b.WriteByte('|')
+		b.WriteString(leefHeaderEscaper.Replace(h))
+	}
+	b.WriteByte('|')
+	b.WriteString(fmt.Sprintf("x%02x", delim))
+	b.WriteByte('|')
+
+	attrs := [][2]string{
+		{"devTime", strconv.FormatInt(e.Time.UnixNano()/1e6, 10)},
+		{"sev", strconv.Itoa(severity(e.Severity, 1))},
+		{"name", e.Name},
+		{"usrName", e.Actor},
+		{"action", e.Action},
+		{"target", e.Target},
+		{"outcome", string(e.Outcome)},
+		{"src", e.SourceIP},
+	}
+	keys := make([]string, len(attrs))
+	for i, kv := range attrs {
+		keys[i] = kv[0]
+	}
+	attrs = append(attrs, sortedExtensions(e.Extensions, delim, keys)...)
+
+	first := true
+	for _, kv := range attrs {
+		if kv[1] == "" {
+			continue
+		}
+		if !first {
+			b.WriteRune(delim)
+		}
+		first = false
+		b.WriteString(kv[0])
+		b.WriteByte('=')
+		b.WriteString(escaper.Replace(kv[1]))
+	}
+	return b.String()
+}
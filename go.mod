@@ -1,5 +1,8 @@
 module slogger
 
-go 1.12
+go 1.15
 
-require github.com/fsouza/go-dockerclient v1.4.4
+require (
+	github.com/fsouza/go-dockerclient v1.4.4
+	golang.org/x/sys v0.0.0-20190710143415-6ec70d6a5542
+)
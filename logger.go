@@ -8,6 +8,7 @@ import (
 	"time"
 
 	sl "slogger/syslog"
+	"slogger/syslog/console"
 )
 
 type Logger interface {
@@ -23,32 +24,38 @@ type Logger interface {
 	Warning(ctx context.Context, m string)
 }
 
+// WithCaller - collect "file:line" of the code calling Logger methods
+func WithCaller() sl.Option {
+	return sl.WithCaller(1)
+}
+
+// WithConsole - write to stderr in human-friendly layout instead of syslog server, for local development
+func WithConsole() sl.Option {
+	return sl.WithConsole(console.Config{})
+}
+
 type logger struct {
 	syslogSender sl.Sender
 }
 
 func New(ctx context.Context, syslogProtocol, syslogAddr, syslogTag string,
-	bufferSizeMessages int, bufferSendPeriod time.Duration, bufferSendCount int) (Logger, error) {
-
-	// Check syslog connection
-	network := syslogProtocol
-	if network == sl.SyslogProtocolRELP {
-		network = sl.SyslogProtocolTCP
-	}
-	slog, err := syslog.Dial(network, syslogAddr, syslog.LOG_WARNING|syslog.LOG_DAEMON, syslogTag)
-	if err != nil {
-		return nil, err
-	}
-	defer slog.Close()
+	bufferSizeMessages int, bufferSendPeriod time.Duration, bufferSendCount int, opts ...sl.Option) (Logger, error) {
 
 	// Init logger
+	var err error
 	l := new(logger)
 	l.syslogSender, err = sl.New(ctx, syslogProtocol, syslogAddr, syslogTag,
-		bufferSizeMessages, bufferSendPeriod, bufferSendCount)
+		bufferSizeMessages, bufferSendPeriod, bufferSendCount, opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	// Check syslog connection (options may change protocol, e.g. WithConsole)
+	if err := l.syslogSender.Probe(); err != nil {
+		l.syslogSender.Close()
+		return nil, err
+	}
+
 	return l, nil
 }
 
@@ -0,0 +1,247 @@
+// Package alert sends notifications (webhook, mail) of high severity records. Alerts are grouped into
+// digests over a window, identical alerts are counted once, and digests are rate limited
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/syslog"
+	"sync"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// Defaults of Config
+const (
+	DefaultMinSeverity = syslog.LOG_CRIT
+	DefaultWindow      = 30 * time.Second
+	DefaultMinInterval = 5 * time.Minute
+	DefaultMaxAlerts   = 50
+)
+
+const severityMask = 0x07
+
+// Notifier - sends digest of alerts (e.g. Webhook, SMTP)
+type Notifier interface {
+	Notify(d *Digest) error
+}
+
+// Config - alerting settings
+type Config struct {
+	// Notifiers - destinations of digests
+	Notifiers []Notifier
+	// MinSeverity - lowest severity raising alert, DefaultMinSeverity (crit) if zero
+	MinSeverity syslog.Priority
+	// Window - time to collect alerts into digest after first alert, DefaultWindow if zero
+	Window time.Duration
+	// MinInterval - minimum time between digests (rate limit), alerts are collected into next digest
+	// meanwhile. DefaultMinInterval if zero, -1 - no limit
+	MinInterval time.Duration
+	// MaxAlerts - maximum distinct alerts of digest, next ones are only counted as suppressed.
+	// DefaultMaxAlerts if zero
+	MaxAlerts int
+	// Key - returns dedupe key of record, records with the same key are one alert with count.
+	// Severity, tag and message by default
+	Key func(r *format.Record) string
+}
+
+// Alert - deduplicated alert of digest
+type Alert struct {
+	// Record - first record of alert
+	Record *format.Record
+	// Count - count of records with key of alert
+	Count int
+	// First, Last - time of first and last record
+	First time.Time
+	Last  time.Time
+}
+
+// Digest - alerts collected over window
+type Digest struct {
+	Alerts []*Alert
+	// Suppressed - count of records dropped because digest already has MaxAlerts alerts
+	Suppressed int
+	// Start, End - time of first alert and of sending
+	Start time.Time
+	End   time.Time
+}
+
+// Title - short summary of digest, used as mail subject and message title
+func (d *Digest) Title() string {
+	if len(d.Alerts) == 1 && d.Suppressed == 0 {
+		a := d.Alerts[0]
+		t := fmt.Sprintf("[%s] %s: %s", format.SeverityName(a.Record.Priority), a.Record.Tag, firstLine(a.Record.Message))
+		if a.Count > 1 {
+			t += fmt.Sprintf(" (x%d)", a.Count)
+		}
+		return t
+	}
+	n := d.Suppressed
+	for _, a := range d.Alerts {
+		n += a.Count
+	}
+	return fmt.Sprintf("%d alerts (%d distinct) since %s", n, len(d.Alerts), d.Start.Format(time.RFC3339))
+}
+
+// Line - one line description of alert
+func (a *Alert) Line() string {
+	r := a.Record
+	s := fmt.Sprintf("[%s] %s", format.SeverityName(r.Priority), r.Tag)
+	if r.Hostname != "" {
+		s += "@" + r.Hostname
+	}
+	s += ": " + firstLine(r.Message)
+	if a.Count > 1 {
+		s += fmt.Sprintf(" (x%d, last %s)", a.Count, a.Last.Format(time.RFC3339))
+	}
+	return s
+}
+
+func firstLine(m string) string {
+	for i := 0; i < len(m); i++ {
+		if m[i] == '\n' || m[i] == '\r' {
+			return m[:i] + " ..."
+		}
+	}
+	return m
+}
+
+func defaultKey(r *format.Record) string {
+	return format.SeverityName(r.Priority) + "\x00" + r.Tag + "\x00" + r.Message
+}
+
+// errClosed - record written after Close
+var errClosed = errors.New("alert: alerter is closed")
+
+// Alerter - collects high severity records into digests and sends them to notifiers
+type Alerter struct {
+	cfg Config
+
+	mu         sync.Mutex
+	alerts     map[string]*Alert
+	order      []*Alert
+	suppressed int
+	start      time.Time
+	timer      *time.Timer
+	lastSent   time.Time
+	closed     bool
+
+	// sendMu - serializes notifications
+	sendMu sync.Mutex
+}
+
+// New - creates alerter
+func New(cfg Config) *Alerter {
+	if cfg.MinSeverity == 0 {
+		cfg.MinSeverity = DefaultMinSeverity
+	}
+	if cfg.Window == 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.MinInterval == 0 {
+		cfg.MinInterval = DefaultMinInterval
+	}
+	if cfg.MaxAlerts <= 0 {
+		cfg.MaxAlerts = DefaultMaxAlerts
+	}
+	if cfg.Key == nil {
+		cfg.Key = defaultKey
+	}
+	return &Alerter{cfg: cfg, alerts: make(map[string]*Alert)}
+}
+
+// Enabled - returns true if records of priority p raise alerts
+func (a *Alerter) Enabled(p syslog.Priority) bool {
+	return p&severityMask <= a.cfg.MinSeverity&severityMask
+}
+
+// WriteRecord - adds record to digest if its severity is MinSeverity or higher
+func (a *Alerter) WriteRecord(r *format.Record) error {
+	if !a.Enabled(r.Priority) {
+		return nil
+	}
+	now := time.Now()
+	ts := r.Timestamp
+	if ts.IsZero() {
+		ts = now
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return errClosed
+	}
+	key := a.cfg.Key(r)
+	if al, ok := a.alerts[key]; ok {
+		al.Count++
+		al.Last = ts
+		return nil
+	}
+	if len(a.order) >= a.cfg.MaxAlerts {
+		a.suppressed++
+		return nil
+	}
+	rec := *r
+	al := &Alert{Record: &rec, Count: 1, First: ts, Last: ts}
+	a.alerts[key] = al
+	a.order = append(a.order, al)
+
+	if a.timer == nil {
+		a.start = now
+		delay := a.cfg.Window
+		if a.cfg.MinInterval > 0 && !a.lastSent.IsZero() {
+			if next := a.lastSent.Add(a.cfg.MinInterval); now.Add(delay).Before(next) {
+				delay = next.Sub(now)
+			}
+		}
+		a.timer = time.AfterFunc(delay, func() {
+			a.Flush()
+		})
+	}
+	return nil
+}
+
+// Flush - sends collected alerts now, without waiting for window and rate limit
+func (a *Alerter) Flush() error {
+	a.mu.Lock()
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+	if len(a.order) == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	d := &Digest{Alerts: a.order, Suppressed: a.suppressed, Start: a.start, End: time.Now()}
+	a.alerts = make(map[string]*Alert)
+	a.order = nil
+	a.suppressed = 0
+	a.lastSent = d.End
+	a.mu.Unlock()
+
+	a.sendMu.Lock()
+	defer a.sendMu.Unlock()
+
+	var err error
+	for _, n := range a.cfg.Notifiers {
+		if nerr := n.Notify(d); nerr != nil {
+			log.Printf("cannot send alert: %v", nerr)
+			if err == nil {
+				err = nerr
+			}
+		}
+	}
+	return err
+}
+
+// Close - sends collected alerts, next records are rejected
+func (a *Alerter) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.mu.Unlock()
+
+	return a.Flush()
+}
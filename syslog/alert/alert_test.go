@@ -0,0 +1,90 @@
+package alert
+
+import (
+	"log/syslog"
+	"sync"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// recorder - notifier storing digests
+type recorder struct {
+	mu      sync.Mutex
+	digests []*Digest
+}
+
+func (r *recorder) Notify(d *Digest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.digests = append(r.digests, d)
+	return nil
+}
+
+func (r *recorder) Digests() []*Digest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*Digest(nil), r.digests...)
+}
+
+func TestAlerter_Digest(t *testing.T) {
+	rec := &recorder{}
+	a := New(Config{Notifiers: []Notifier{rec}, Window: 30 * time.Millisecond, MaxAlerts: 2})
+	defer a.Close()
+
+	a.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Tag: "app", Message: "not an alert"})
+	for i := 0; i < 3; i++ {
+		a.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Tag: "app", Message: "disk full"})
+	}
+	a.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Tag: "app", Message: "panic"})
+	a.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Tag: "app", Message: "third distinct"})
+
+	if n := len(rec.Digests()); n != 0 {
+		t.Errorf("expect no digest before window ends, got: %d", n)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	ds := rec.Digests()
+	if len(ds) != 1 {
+		t.Fatalf("expect 1 digest, got: %d", len(ds))
+	}
+	d := ds[0]
+	if len(d.Alerts) != 2 || d.Suppressed != 1 {
+		t.Fatalf("expect 2 alerts and 1 suppressed, got: %d, %d", len(d.Alerts), d.Suppressed)
+	}
+	if d.Alerts[0].Count != 3 || d.Alerts[0].Record.Message != "disk full" {
+		t.Errorf("expect deduplicated alert with count 3, got: %q x%d", d.Alerts[0].Record.Message, d.Alerts[0].Count)
+	}
+	if title := d.Title(); title != "5 alerts (2 distinct) since "+d.Start.Format(time.RFC3339) {
+		t.Errorf("unexpected title: %s", title)
+	}
+}
+
+func TestAlerter_RateLimit(t *testing.T) {
+	rec := &recorder{}
+	a := New(Config{Notifiers: []Notifier{rec}, Window: 10 * time.Millisecond, MinInterval: 300 * time.Millisecond})
+
+	a.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Tag: "app", Message: "first"})
+	time.Sleep(50 * time.Millisecond)
+	a.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Tag: "app", Message: "second"})
+	time.Sleep(50 * time.Millisecond)
+
+	if n := len(rec.Digests()); n != 1 {
+		t.Errorf("expect second digest delayed by rate limit, got: %d digests", n)
+	}
+	a.Close()
+
+	ds := rec.Digests()
+	if len(ds) != 2 || ds[1].Alerts[0].Record.Message != "second" {
+		t.Fatalf("expect pending alert sent on close, got: %d digests", len(ds))
+	}
+	if title := ds[1].Title(); title != "[crit] app: second" {
+		t.Errorf("unexpected title: %s", title)
+	}
+	if err := a.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: "late"}); err == nil {
+		t.Errorf("expect error after close, got: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SMTP - sends digest as plain text mail
+type SMTP struct {
+	// Addr - "host:port" of mail server, STARTTLS is used if server supports it
+	Addr string
+	// Auth - authentication (e.g. smtp.PlainAuth), none if nil
+	Auth smtp.Auth
+	From string
+	To   []string
+	// SubjectPrefix - prepended to digest title (e.g. "[prod]")
+	SubjectPrefix string
+}
+
+// Notify - implements Notifier
+func (s *SMTP) Notify(d *Digest) error {
+	if len(s.To) == 0 {
+		return errors.New("alert: no mail recipients")
+	}
+	return smtp.SendMail(s.Addr, s.Auth, s.From, s.To, s.message(d))
+}
+
+// message - RFC 5322 message of digest with CRLF line endings
+func (s *SMTP) message(d *Digest) []byte {
+	subject := d.Title()
+	if s.SubjectPrefix != "" {
+		subject = s.SubjectPrefix + " " + subject
+	}
+	b := new(strings.Builder)
+	header := func(k, v string) {
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(v)
+		b.WriteString("\r\n")
+	}
+	header("From", s.From)
+	header("To", strings.Join(s.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", d.End.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+
+	body := new(strings.Builder)
+	body.WriteString(Text(d))
+	for _, a := range d.Alerts {
+		r := a.Record
+		body.WriteString("\n\n")
+		body.WriteString(a.Line())
+		if r.Caller != "" {
+			body.WriteString("\ncaller: " + r.Caller)
+		}
+		keys := make([]string, 0, len(r.Fields))
+		for k := range r.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(body, "\n%s: %v", k, r.Fields[k])
+		}
+		if strings.ContainsAny(r.Message, "\r\n") {
+			body.WriteString("\n\n" + r.Message)
+		}
+	}
+	b.WriteString(crlf.Replace(body.String()))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// crlf - normalizes line endings of mail body
+var crlf = strings.NewReplacer("\r\n", "\r\n", "\r", "\r\n", "\n", "\r\n")
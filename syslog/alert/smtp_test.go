@@ -0,0 +1,122 @@
+package alert
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpServer - fake SMTP server storing recipients and data of mails
+type smtpServer struct {
+	ln    net.Listener
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	rcpts []string
+	data  []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln}
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+func (s *smtpServer) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *smtpServer) accept() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *smtpServer) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	reply := func(line string) {
+		c.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var data []string
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data = append(data, l)
+			}
+			s.mu.Lock()
+			s.data = append(s.data, strings.Join(data, ""))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTP_Notify(t *testing.T) {
+	srv := newSMTPServer(t)
+	defer srv.Close()
+
+	m := &SMTP{Addr: srv.ln.Addr().String(), From: "slogger@example.com",
+		To: []string{"oncall@example.com", "ops@example.com"}, SubjectPrefix: "[prod]"}
+	if err := m.Notify(testDigest()); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.rcpts) != 2 || srv.rcpts[0] != "oncall@example.com" {
+		t.Errorf("unexpected recipients: %v", srv.rcpts)
+	}
+	if len(srv.data) != 1 {
+		t.Fatalf("expect 1 mail, got: %d", len(srv.data))
+	}
+	data := srv.data[0]
+	if !strings.Contains(data, "Subject: [prod] 3 alerts (2 distinct)") {
+		t.Errorf("expect subject with prefix, got: %q", data)
+	}
+	if !strings.Contains(data, "\r\ndisk: /var\r\n") || !strings.Contains(data, "\r\npanic\r\nstack\r\n") {
+		t.Errorf("expect fields and full multiline message, got: %q", data)
+	}
+}
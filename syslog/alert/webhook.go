@@ -0,0 +1,131 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/httppush"
+)
+
+// WebhookFormat - payload layout of webhook
+type WebhookFormat int
+
+const (
+	// WebhookJSON - digest as JSON object: title, alerts (with fields and counts) and suppressed count
+	WebhookJSON WebhookFormat = iota
+	// WebhookSlack - Slack (Mattermost, Rocket.Chat) incoming webhook message {"text": ...}
+	WebhookSlack
+)
+
+// Webhook - POSTs digest to URL
+type Webhook struct {
+	URL    string
+	Format WebhookFormat
+	// Header - additional request headers (e.g. Authorization)
+	Header http.Header
+	// Retry - retries of 429 and 5xx responses and network errors
+	Retry httppush.Retry
+	// Client - HTTP client, httppush.DefaultClient if nil
+	Client *http.Client
+}
+
+type webhookAlert struct {
+	Severity string        `json:"severity"`
+	Tag      string        `json:"tag"`
+	Host     string        `json:"host,omitempty"`
+	Message  string        `json:"message"`
+	Caller   string        `json:"caller,omitempty"`
+	Fields   format.Fields `json:"fields,omitempty"`
+	TraceID  string        `json:"trace_id,omitempty"`
+	Count    int           `json:"count"`
+	First    time.Time     `json:"first"`
+	Last     time.Time     `json:"last"`
+}
+
+type webhookDigest struct {
+	Title      string         `json:"title"`
+	Alerts     []webhookAlert `json:"alerts"`
+	Suppressed int            `json:"suppressed"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify - implements Notifier
+func (w *Webhook) Notify(d *Digest) error {
+	body, err := json.Marshal(w.payload(d))
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	for k, v := range w.Header {
+		header[k] = v
+	}
+	return httppush.Post(context.Background(), httppush.Request{Client: w.Client, URL: w.URL, Header: header, Body: body}, w.Retry)
+}
+
+func (w *Webhook) payload(d *Digest) interface{} {
+	if w.Format == WebhookSlack {
+		return slackMessage{Text: Text(d)}
+	}
+	p := webhookDigest{Title: d.Title(), Suppressed: d.Suppressed, Start: d.Start, End: d.End}
+	for _, a := range d.Alerts {
+		r := a.Record
+		p.Alerts = append(p.Alerts, webhookAlert{
+			Severity: format.SeverityName(r.Priority),
+			Tag:      r.Tag,
+			Host:     r.Hostname,
+			Message:  r.Message,
+			Caller:   r.Caller,
+			Fields:   jsonFields(r.Fields),
+			TraceID:  r.TraceID,
+			Count:    a.Count,
+			First:    a.First,
+			Last:     a.Last,
+		})
+	}
+	return p
+}
+
+// jsonFields - returns copy of fields with values not supported by JSON formatted as strings
+func jsonFields(f format.Fields) format.Fields {
+	if len(f) == 0 {
+		return nil
+	}
+	c := make(format.Fields, len(f))
+	for k, v := range f {
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprint(v)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// Text - plain text of digest: title and one line per alert
+func Text(d *Digest) string {
+	b := new(strings.Builder)
+	b.WriteString(d.Title())
+	if len(d.Alerts) == 1 && d.Suppressed == 0 {
+		return b.String()
+	}
+	for _, a := range d.Alerts {
+		b.WriteString("\n• ")
+		b.WriteString(a.Line())
+	}
+	if d.Suppressed > 0 {
+		b.WriteString("\n• ... and ")
+		b.WriteString(strconv.Itoa(d.Suppressed))
+		b.WriteString(" more")
+	}
+	return b.String()
+}
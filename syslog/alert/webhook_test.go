@@ -0,0 +1,82 @@
+package alert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log/syslog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+func testDigest() *Digest {
+	now := time.Now()
+	return &Digest{
+		Alerts: []*Alert{
+			{Record: &format.Record{Priority: syslog.LOG_CRIT, Tag: "app", Hostname: "web-1", Message: "disk full",
+				Fields: format.Fields{"disk": "/var", "fn": func() {}}}, Count: 2, First: now, Last: now},
+			{Record: &format.Record{Priority: syslog.LOG_EMERG, Tag: "app", Message: "panic\nstack"}, Count: 1, First: now, Last: now},
+		},
+		Start: now,
+		End:   now,
+	}
+}
+
+func TestWebhook_JSON(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	w := &Webhook{URL: srv.URL, Header: http.Header{"Authorization": {"Bearer token"}}}
+	if err := w.Notify(testDigest()); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	var p webhookDigest
+	if err := json.Unmarshal(<-bodies, &p); err != nil {
+		t.Fatalf("expect JSON payload, got: %v", err)
+	}
+	if len(p.Alerts) != 2 || p.Alerts[0].Count != 2 || p.Alerts[0].Severity != "crit" || p.Alerts[0].Host != "web-1" {
+		t.Errorf("unexpected alerts: %+v", p.Alerts)
+	}
+	if p.Alerts[0].Fields["disk"] != "/var" {
+		t.Errorf("expect fields, got: %v", p.Alerts[0].Fields)
+	}
+}
+
+func TestWebhook_Slack(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	w := &Webhook{URL: srv.URL, Format: WebhookSlack}
+	if err := w.Notify(testDigest()); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	var m slackMessage
+	if err := json.Unmarshal(<-bodies, &m); err != nil {
+		t.Fatalf("expect JSON payload, got: %v", err)
+	}
+	lines := strings.Split(m.Text, "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "3 alerts (2 distinct)") {
+		t.Fatalf("unexpected text: %q", m.Text)
+	}
+	if !strings.HasPrefix(lines[1], "• [crit] app@web-1: disk full (x2") || lines[2] != "• [emerg] app: panic ..." {
+		t.Errorf("unexpected alert lines: %q", lines[1:])
+	}
+}
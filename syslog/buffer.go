@@ -5,6 +5,7 @@ import (
 	"fmt"
 	slog "log/syslog"
 	"sync"
+	"time"
 )
 
 type messageBuffer struct {
@@ -14,10 +15,11 @@ type messageBuffer struct {
 }
 
 type bufferRecord struct {
-	ctx   context.Context
-	ts    string
-	level slog.Priority
-	value string
+	ctx    context.Context
+	ts     time.Time
+	level  slog.Priority
+	value  string
+	caller string
 }
 
 func newMessageBuffer(size int) *messageBuffer {
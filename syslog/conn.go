@@ -0,0 +1,85 @@
+package syslog
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultIdleTimeout - time without messages after which connection kept between batches is closed
+const DefaultIdleTimeout = time.Minute
+
+// ConnChecker - optional interface of SyslogWriter, checks that connection kept between batches
+// is not broken (e.g. closed by server) before it is reused. Writers without it are reused until
+// write fails
+type ConnChecker interface {
+	CheckConn() error
+}
+
+// connection - returns writer of active endpoint. Writer of previous batch is reused if endpoint
+// was not switched, its "srv://" address resolves to the same targets and connection is not broken,
+// new connection is made otherwise
+func (s *syslog) connection(ctx context.Context) (SyslogWriter, Endpoint, error) {
+	if s.conn != nil {
+		if _, i := s.failover.current(); i != s.connIndex {
+			s.closeConn()
+		} else if s.srvChanged(ctx) {
+			log.Printf("syslog endpoint %s resolves to new targets, reconnecting", s.connEndpoint)
+			s.closeConn()
+		} else if c, ok := s.conn.(ConnChecker); ok {
+			if err := c.CheckConn(); err != nil {
+				log.Printf("syslog connection to %s is broken, reconnecting: %v", s.connEndpoint, err)
+				s.closeConn()
+			}
+		}
+	}
+	if s.conn == nil {
+		w, e, i, err := s.dialEndpoint(ctx)
+		if err != nil {
+			return nil, e, err
+		}
+		s.conn, s.connEndpoint, s.connIndex = w, e, i
+		s.connTargets = s.srvTargets(ctx, e.Addr)
+		atomic.AddUint64(&s.stats.Connects, 1)
+	}
+	s.connUsed = time.Now()
+	return s.conn, s.connEndpoint, nil
+}
+
+// Flusher - optional interface of SyslogWriter, Flush is called after every batch written to
+// connection kept between batches (e.g. file writer fsyncs with file.SyncClose)
+type Flusher interface {
+	Flush() error
+}
+
+// flushConn - flushes writer of kept connection after batch
+func (s *syslog) flushConn() error {
+	f, ok := s.conn.(Flusher)
+	if !ok {
+		return nil
+	}
+	err := f.Flush()
+	if err != nil {
+		log.Printf("cannot flush syslog connection to %s: %v", s.connEndpoint, err)
+	}
+	return err
+}
+
+// closeIdleConn - closes connection unused for idle timeout
+func (s *syslog) closeIdleConn(now time.Time) {
+	if s.conn != nil && now.Sub(s.connUsed) >= s.idleTimeout {
+		s.closeConn()
+	}
+}
+
+// closeConn - closes connection kept between batches
+func (s *syslog) closeConn() {
+	if s.conn == nil {
+		return
+	}
+	if err := s.conn.Close(); err != nil {
+		log.Printf("cannot close syslog connection to %s: %v", s.connEndpoint, err)
+	}
+	s.conn = nil
+}
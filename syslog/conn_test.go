@@ -0,0 +1,184 @@
+package syslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	slog "log/syslog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/mock"
+	"slogger/syslog/relp/relptest"
+)
+
+func TestSyslog_PersistentConnection(t *testing.T) {
+	srv, err := relptest.NewServer()
+	if err != nil {
+		t.Fatalf("cannot start server: %v", err)
+	}
+	defer srv.Close()
+
+	s, err := New(context.Background(), SyslogProtocolRELP, srv.Addr, "tag", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		s.Send(context.Background(), slog.LOG_ERR, "batch")
+		time.Sleep(30 * time.Millisecond)
+	}
+	if n := srv.Sessions(); n != 1 {
+		t.Errorf("expect batches sent over 1 connection, got: %d sessions", n)
+	}
+
+	// connection closed by server is detected before it is reused, messages are not lost
+	srv.CloseClientConnections()
+	time.Sleep(10 * time.Millisecond)
+	s.Send(context.Background(), slog.LOG_ERR, "after reconnect")
+	s.Close()
+
+	if n := srv.Sessions(); n != 2 {
+		t.Errorf("expect reconnect after broken connection, got: %d sessions", n)
+	}
+	if n := len(srv.Messages()); n != 4 {
+		t.Errorf("expect 4 messages, got: %d", n)
+	}
+	if n := s.Stats().Connects; n != 2 {
+		t.Errorf("expect 2 connects, got: %d", n)
+	}
+}
+
+func TestSyslog_IdleTimeout(t *testing.T) {
+	srv, err := relptest.NewServer()
+	if err != nil {
+		t.Fatalf("cannot start server: %v", err)
+	}
+	defer srv.Close()
+
+	s, err := New(context.Background(), SyslogProtocolRELP, srv.Addr, "tag", 8, 10*time.Millisecond, 8,
+		WithIdleTimeout(40*time.Millisecond))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	defer s.Close()
+
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	time.Sleep(150 * time.Millisecond)
+	if n := s.Stats().Connects; n != 1 {
+		t.Errorf("expect 1 connect, got: %d", n)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "after idle")
+	time.Sleep(50 * time.Millisecond)
+	if n := s.Stats().Connects; n != 2 {
+		t.Errorf("expect new connection after idle timeout, got: %d connects", n)
+	}
+}
+
+// failingWriter - writer failing to send messages
+type failingWriter struct {
+	mock.SyslogWriter
+	closed int32
+}
+
+func (w *failingWriter) Err(m string) error {
+	return errors.New("broken")
+}
+
+func (w *failingWriter) Close() error {
+	atomic.AddInt32(&w.closed, 1)
+	return nil
+}
+
+func TestSyslog_CloseConnOnWriteError(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "2", "3", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	sl := s.(*syslog)
+	w := &failingWriter{}
+	sl.SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return w, true
+	})
+
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	time.Sleep(30 * time.Millisecond)
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	s.Close()
+
+	st := s.Stats()
+	if n := atomic.LoadInt32(&w.closed); n < 2 || uint64(n) != st.Connects {
+		t.Errorf("expect writer closed after every failed attempt, got %d closes of %d connects", n, st.Connects)
+	}
+	if st.Dropped != 2 {
+		t.Errorf("expect records retried until close, got %d dropped", st.Dropped)
+	}
+}
+
+// droppingWriter - writer which cannot send records at all
+type droppingWriter struct {
+	mock.SyslogWriter
+	writes int32
+}
+
+func (w *droppingWriter) WriteRecord(r *format.Record) error {
+	atomic.AddInt32(&w.writes, 1)
+	return fmt.Errorf("%w: too large", format.ErrDropped)
+}
+
+func TestSyslog_WriterDropped(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "2", "3", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	w := &droppingWriter{}
+	s.(*syslog).SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return w, true
+	})
+
+	s.Send(context.Background(), slog.LOG_ERR, "unsendable")
+	time.Sleep(50 * time.Millisecond)
+	s.Close()
+
+	if n := atomic.LoadInt32(&w.writes); n != 1 {
+		t.Errorf("expect dropped record not resent, got %d writes", n)
+	}
+	if st := s.Stats(); st.Dropped != 1 || st.Connects != 1 {
+		t.Errorf("expect 1 dropped record over kept connection, got: %+v", st)
+	}
+}
+
+// flushingWriter - writer counting flushes
+type flushingWriter struct {
+	mock.SyslogWriter
+	flushes int32
+}
+
+func (w *flushingWriter) Flush() error {
+	atomic.AddInt32(&w.flushes, 1)
+	return nil
+}
+
+func TestSyslog_FlushAfterBatch(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "2", "3", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	w := &flushingWriter{}
+	s.(*syslog).SetDialMethod(func(_ context.Context, _, _, _ string) (SyslogWriter, bool) {
+		return w, true
+	})
+
+	for i := 0; i < 2; i++ {
+		s.Send(context.Background(), slog.LOG_ERR, "batch")
+		time.Sleep(30 * time.Millisecond)
+	}
+	s.Close()
+	if n := s.Stats().Connects; n != 1 {
+		t.Errorf("expect 1 connect, got: %d", n)
+	}
+	if n := atomic.LoadInt32(&w.flushes); n != 2 {
+		t.Errorf("expect flush after every batch, got: %d", n)
+	}
+}
@@ -0,0 +1,38 @@
+// Package conncheck detects broken stream connections kept open between batches
+package conncheck
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// ProbeTimeout - time to wait for EOF of closed connection in ReadProbe
+const ProbeTimeout = time.Millisecond
+
+// ErrUnexpectedData - idle connection received data (e.g. RELP "serverclose")
+var ErrUnexpectedData = errors.New("unexpected data on idle connection")
+
+// ReadProbe - checks idle connection with short read: timeout means that connection is alive,
+// EOF or other error that it is broken (e.g. closed by server). Write to such connection may
+// succeed and lose data, so connection should be checked before it is reused.
+// Data received by idle connection is reported as ErrUnexpectedData, connection should be closed then
+func ReadProbe(c net.Conn) error {
+	if err := c.SetReadDeadline(time.Now().Add(ProbeTimeout)); err != nil {
+		return err
+	}
+	defer c.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+	n, err := c.Read(b[:])
+	if n > 0 {
+		return ErrUnexpectedData
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return nil
+	}
+	if err == nil {
+		return ErrUnexpectedData
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package conncheck
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReadProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			accepted <- c
+		}
+	}()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	srv := <-accepted
+
+	if err := ReadProbe(c); err != nil {
+		t.Errorf("expect alive connection, got: %v", err)
+	}
+
+	srv.Write([]byte("x"))
+	time.Sleep(10 * time.Millisecond)
+	if err := ReadProbe(c); err != ErrUnexpectedData {
+		t.Errorf("expect ErrUnexpectedData, got: %v", err)
+	}
+
+	srv.Close()
+	time.Sleep(10 * time.Millisecond)
+	if err := ReadProbe(c); err == nil {
+		t.Errorf("expect error of connection closed by server, got: %v", err)
+	}
+}
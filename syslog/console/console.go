@@ -0,0 +1,206 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// ColorMode - when severity is colorized
+type ColorMode int
+
+const (
+	// ColorAuto - colorize if output is a terminal and NO_COLOR is not set (default)
+	ColorAuto ColorMode = iota
+	// ColorAlways - always colorize
+	ColorAlways
+	// ColorNever - never colorize
+	ColorNever
+)
+
+// DefaultTimeFormat - fixed width time format, so columns are aligned
+const DefaultTimeFormat = "2006-01-02 15:04:05.000"
+
+const severityMask = 0x07
+
+// Config - console output settings
+type Config struct {
+	// Output - destination, os.Stderr if nil
+	Output io.Writer
+	// Color - colorize severity, ColorAuto by default
+	Color ColorMode
+	// TimeFormat - time layout, DefaultTimeFormat if empty
+	TimeFormat string
+}
+
+// severityLabels - upper case severities padded to the same width
+var severityLabels = [...]string{"EMERG  ", "ALERT  ", "CRIT   ", "ERROR  ", "WARNING", "NOTICE ", "INFO   ", "DEBUG  "}
+
+// severityColors - ANSI SGR parameters of severities
+var severityColors = [...]string{"1;31", "1;31", "1;31", "31", "33", "36", "32", "90"}
+
+// Writer - human-friendly writer for local development: time, severity, tag, message, fields
+// and caller on one line. It implements syslog.SyslogWriter and syslog.RecordWriter,
+// so can be used as sender sink
+type Writer struct {
+	priority   syslog.Priority
+	tag        string
+	color      bool
+	timeFormat string
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// New - creates writer to cfg.Output
+func New(priority syslog.Priority, tag string, cfg Config) *Writer {
+	if cfg.Output == nil {
+		cfg.Output = os.Stderr
+	}
+	if cfg.TimeFormat == "" {
+		cfg.TimeFormat = DefaultTimeFormat
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+
+	color := cfg.Color == ColorAlways
+	if cfg.Color == ColorAuto {
+		_, noColor := os.LookupEnv("NO_COLOR")
+		color = !noColor && IsTerminal(cfg.Output)
+	}
+
+	return &Writer{
+		priority:   priority,
+		tag:        tag,
+		color:      color,
+		timeFormat: cfg.TimeFormat,
+		out:        cfg.Output,
+	}
+}
+
+// IsTerminal - returns true if w is a character device (terminal)
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Close - does nothing, output is not owned by writer
+func (w *Writer) Close() error {
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.WriteRecord(&format.Record{Priority: w.priority, Message: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - console output keeps line breaks of multi-line messages, so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - writes record as one line: "<time> <SEVERITY> <tag>: <message> key=value ... (caller)"
+func (w *Writer) WriteRecord(r *format.Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ts := r.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	tag := r.Tag
+	if tag == "" {
+		tag = w.tag
+	}
+	sev := r.Priority & severityMask
+
+	b := new(bytes.Buffer)
+	b.WriteString(ts.Format(w.timeFormat))
+	b.WriteByte(' ')
+	if w.color {
+		fmt.Fprintf(b, "\x1b[%sm%s\x1b[0m", severityColors[sev], severityLabels[sev])
+	} else {
+		b.WriteString(severityLabels[sev])
+	}
+	b.WriteByte(' ')
+	b.WriteString(tag)
+	b.WriteString(": ")
+	b.WriteString(strings.TrimSuffix(r.Message, "\n"))
+	writeFields(b, r.Fields)
+	if r.Caller != "" {
+		b.WriteString(" (")
+		b.WriteString(r.Caller)
+		b.WriteByte(')')
+	}
+	b.WriteByte('\n')
+
+	_, err := w.out.Write(b.Bytes())
+	return err
+}
+
+// writeFields - writes fields sorted by key as " key=value", values with spaces or quotes are quoted
+func writeFields(b *bytes.Buffer, fields format.Fields) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := fmt.Sprint(fields[k])
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		b.WriteByte(' ')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(v)
+	}
+}
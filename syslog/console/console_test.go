@@ -0,0 +1,54 @@
+package console
+
+import (
+	"bytes"
+	"log/syslog"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+func TestWriter_WriteRecord(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := New(syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{Output: b})
+
+	err := w.WriteRecord(&format.Record{
+		Priority:  syslog.LOG_ERR,
+		Timestamp: time.Date(2019, 7, 10, 14, 34, 15, 123000000, time.UTC),
+		Message:   "Test message\n",
+		Caller:    "main.go:42",
+		Fields:    format.Fields{"user": "john doe", "id": 7},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	w.Info("second")
+
+	lines := strings.Split(b.String(), "\n")
+	expect := `2019-07-10 14:34:15.123 ERROR   tag: Test message id=7 user="john doe" (main.go:42)`
+	if lines[0] != expect {
+		t.Errorf("expect %q, got: %q", expect, lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " INFO    tag: second") || len(lines[1]) != len(DefaultTimeFormat)+len(" INFO    tag: second") {
+		t.Errorf("unexpected line: %q", lines[1])
+	}
+}
+
+func TestWriter_Color(t *testing.T) {
+	b := new(bytes.Buffer)
+	if IsTerminal(b) {
+		t.Errorf("expect buffer is not a terminal")
+	}
+	New(syslog.LOG_DAEMON, "tag", Config{Output: b}).Warning("plain")
+	New(syslog.LOG_DAEMON, "tag", Config{Output: b, Color: ColorAlways}).Warning("colored")
+
+	lines := strings.Split(b.String(), "\n")
+	if strings.Contains(lines[0], "\x1b[") {
+		t.Errorf("expect no colors, got: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "\x1b[33mWARNING\x1b[0m tag: colored") {
+		t.Errorf("expect colored severity, got: %q", lines[1])
+	}
+}
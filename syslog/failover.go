@@ -0,0 +1,246 @@
+package syslog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxFailures - consecutive dial failures of endpoint before switching to next one
+	DefaultMaxFailures = 3
+	// DefaultHealthCheckInterval - period of checking endpoints preferred to active one
+	DefaultHealthCheckInterval = 10 * time.Second
+	// DefaultRetryDelay - delay between dial attempts
+	DefaultRetryDelay = time.Second
+	// DefaultMaxRetryBackoff - maximum delay between retries of batch no endpoint accepted
+	DefaultMaxRetryBackoff = 30 * time.Second
+)
+
+// errNoEndpoint - all endpoints failed to dial
+var errNoEndpoint = errors.New("no syslog endpoint reachable")
+
+// Endpoint - syslog server. Endpoints with lower Priority are preferred
+type Endpoint struct {
+	// Protocol - protocol of endpoint, protocol passed to New if empty
+	Protocol string
+	Addr     string
+	Priority int
+}
+
+func (e Endpoint) String() string {
+	return e.Protocol + "://" + e.Addr
+}
+
+// FailoverConfig - failover and failback settings of endpoints set with WithEndpoints
+type FailoverConfig struct {
+	// MaxFailures - consecutive dial failures before switching to next endpoint, DefaultMaxFailures if 0
+	MaxFailures int
+	// HealthCheckInterval - period of checking (by dial) endpoints preferred to active one,
+	// sender fails back to the first healthy one. DefaultHealthCheckInterval if 0
+	HealthCheckInterval time.Duration
+	// RetryDelay - delay between dial attempts, DefaultRetryDelay if 0
+	RetryDelay time.Duration
+	// MaxRetryBackoff - maximum delay between retries of batch after full pass over endpoints failed
+	// (the delay starts with send period and doubles), DefaultMaxRetryBackoff if 0
+	MaxRetryBackoff time.Duration
+	// OnBatch - called with endpoint every batch of records was sent to (part of batch resent
+	// after write failure is reported with endpoint it was resent to)
+	OnBatch func(e Endpoint, records int)
+}
+
+// WithEndpoints - add failover endpoints to syslog server passed to New (which has priority 0).
+// Batches are sent to the active endpoint; after FailoverConfig.MaxFailures dial failures the next
+// endpoint by priority becomes active
+func WithEndpoints(endpoints ...Endpoint) Option {
+	return func(s *syslog) {
+		s.endpoints = append(s.endpoints, endpoints...)
+	}
+}
+
+// WithFailover - set failover settings of endpoints
+func WithFailover(cfg FailoverConfig) Option {
+	return func(s *syslog) {
+		s.failoverConfig = cfg
+	}
+}
+
+// failover - endpoints ordered by priority and active one
+type failover struct {
+	cfg       FailoverConfig
+	endpoints []Endpoint
+
+	mu       sync.Mutex
+	active   int
+	failures int
+}
+
+func newFailover(primary Endpoint, endpoints []Endpoint, cfg FailoverConfig) *failover {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = DefaultMaxFailures
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultRetryDelay
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = DefaultMaxRetryBackoff
+	}
+
+	list := []Endpoint{primary}
+	for _, e := range endpoints {
+		if e.Protocol == "" {
+			e.Protocol = primary.Protocol
+		}
+		list = append(list, e)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority < list[j].Priority
+	})
+	return &failover{cfg: cfg, endpoints: list}
+}
+
+// current - returns active endpoint and its index
+func (f *failover) current() (Endpoint, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.endpoints[f.active], f.active
+}
+
+// success - resets failures of endpoint i
+func (f *failover) success(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active == i {
+		f.failures = 0
+	}
+}
+
+// failure - counts dial failure of endpoint i, returns true if next endpoint became active
+func (f *failover) failure(i int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active != i || len(f.endpoints) < 2 {
+		return false
+	}
+	f.failures++
+	if f.failures < f.cfg.MaxFailures {
+		return false
+	}
+	f.active = (f.active + 1) % len(f.endpoints)
+	f.failures = 0
+	log.Printf("syslog endpoint %s failed, switching to %s", f.endpoints[i], f.endpoints[f.active])
+	return true
+}
+
+// switchFrom - makes endpoint after i active without counting failures, returns true if switched
+func (f *failover) switchFrom(i int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active != i || len(f.endpoints) < 2 {
+		return false
+	}
+	f.active = (f.active + 1) % len(f.endpoints)
+	f.failures = 0
+	log.Printf("syslog endpoint %s write failed, switching to %s", f.endpoints[i], f.endpoints[f.active])
+	return true
+}
+
+// failback - makes endpoint i active if it is preferred to active one
+func (f *failover) failback(i int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i >= f.active {
+		return false
+	}
+	log.Printf("syslog endpoint %s recovered, switching back from %s", f.endpoints[i], f.endpoints[f.active])
+	f.active = i
+	f.failures = 0
+	return true
+}
+
+// dialEndpoint - dials active endpoint, switching endpoints on failures. Gives up after one full
+// pass over endpoints (MaxFailures attempts of each) or when ctx is done
+func (s *syslog) dialEndpoint(ctx context.Context) (SyslogWriter, Endpoint, int, error) {
+	attempts := s.failover.cfg.MaxFailures * len(s.failover.endpoints)
+	for a := 1; ; a++ {
+		e, i := s.failover.current()
+		if w, ok := s.dialAddr(ctx, e.Protocol, e.Addr); ok {
+			s.failover.success(i)
+			return w, e, i, nil
+		}
+		if s.failover.failure(i) {
+			atomic.AddUint64(&s.stats.Failovers, 1)
+		}
+		if a >= attempts {
+			return nil, e, i, errNoEndpoint
+		}
+
+		t := time.NewTimer(s.failover.cfg.RetryDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, e, i, ctx.Err()
+		}
+	}
+}
+
+// retryBackoff - returns delay before next retry of batch after previous delay d
+func (f *failover) retryBackoff(d, min time.Duration) time.Duration {
+	d *= 2
+	if d < min {
+		d = min
+	}
+	if d > f.cfg.MaxRetryBackoff {
+		d = f.cfg.MaxRetryBackoff
+	}
+	return d
+}
+
+// writeFailure - makes endpoint after i active because write to i failed on established connection
+func (s *syslog) writeFailure(i int) {
+	if s.failover.switchFrom(i) {
+		atomic.AddUint64(&s.stats.Failovers, 1)
+	}
+}
+
+// healthCheck - periodically dials endpoints preferred to active one and fails back to the first
+// reachable one
+func (s *syslog) healthCheck(ctx context.Context) {
+	defer s.wgSyslogSend.Done()
+
+	t := time.NewTicker(s.failover.cfg.HealthCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			_, active := s.failover.current()
+			for i := 0; i < active; i++ {
+				e := s.failover.endpoints[i]
+				w, ok := s.dialAddr(ctx, e.Protocol, e.Addr)
+				if !ok {
+					continue
+				}
+				w.Close()
+				if s.failover.failback(i) {
+					atomic.AddUint64(&s.stats.Failbacks, 1)
+				}
+				break
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
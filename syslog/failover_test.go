@@ -0,0 +1,232 @@
+package syslog
+
+import (
+	"context"
+	slog "log/syslog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"slogger/syslog/mock"
+)
+
+func TestSyslog_failover(t *testing.T) {
+	batches := make(chan string, 8)
+	s, err := New(context.Background(), SyslogProtocolTCP, "primary:514", "tag", 8, 10*time.Millisecond, 8,
+		WithEndpoints(Endpoint{Addr: "backup:514", Priority: 1}),
+		WithFailover(FailoverConfig{
+			MaxFailures:         2,
+			RetryDelay:          time.Millisecond,
+			HealthCheckInterval: 20 * time.Millisecond,
+			OnBatch: func(e Endpoint, records int) {
+				batches <- e.String()
+			},
+		}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	defer s.Close()
+
+	var primaryDown int32 = 1
+	mockWriter := &mock.SyslogWriter{}
+	s.(*syslog).SetDialMethod(func(_ context.Context, protocol, addr, _ string) (SyslogWriter, bool) {
+		if addr == "primary:514" && atomic.LoadInt32(&primaryDown) == 1 {
+			return nil, false
+		}
+		return mockWriter, true
+	})
+
+	waitBatch := func() string {
+		select {
+		case e := <-batches:
+			return e
+		case <-time.After(time.Second):
+			t.Fatalf("batch not sent")
+		}
+		return ""
+	}
+
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	if e := waitBatch(); e != "tcp://backup:514" {
+		t.Errorf("expect batch sent to backup, got: %s", e)
+	}
+	if st := s.Stats(); st.Failovers != 1 {
+		t.Errorf("expect 1 failover, got: %d", st.Failovers)
+	}
+
+	atomic.StoreInt32(&primaryDown, 0)
+	for i := 0; i < 100 && s.Stats().Failbacks == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := s.Stats(); st.Failbacks != 1 {
+		t.Fatalf("expect 1 failback, got: %d", st.Failbacks)
+	}
+
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	if e := waitBatch(); e != "tcp://primary:514" {
+		t.Errorf("expect batch sent to primary, got: %s", e)
+	}
+	if c := mockWriter.Messages(slog.LOG_ERR); c != 2 {
+		t.Errorf("expect 2 messages, got: %d", c)
+	}
+}
+
+func Test_newFailover(t *testing.T) {
+	f := newFailover(Endpoint{Protocol: SyslogProtocolRELP, Addr: "a"}, []Endpoint{
+		{Addr: "c", Priority: 2},
+		{Protocol: SyslogProtocolTCP, Addr: "b", Priority: 1},
+		{Addr: "d", Priority: -1},
+	}, FailoverConfig{})
+
+	expect := []string{"relp://d", "relp://a", "tcp://b", "relp://c"}
+	for i, e := range f.endpoints {
+		if e.String() != expect[i] {
+			t.Errorf("expect endpoint %d %s, got: %s", i, expect[i], e)
+		}
+	}
+	if f.cfg.MaxFailures != DefaultMaxFailures || f.cfg.RetryDelay != DefaultRetryDelay {
+		t.Errorf("expect default settings, got: %+v", f.cfg)
+	}
+}
+
+func TestSyslog_failoverAllDown(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "primary:514", "tag", 8, 10*time.Millisecond, 8,
+		WithEndpoints(Endpoint{Addr: "backup:514", Priority: 1}),
+		WithFailover(FailoverConfig{MaxFailures: 2, RetryDelay: time.Hour}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+
+	var dials int32
+	s.(*syslog).SetDialMethod(func(_ context.Context, protocol, addr, _ string) (SyslogWriter, bool) {
+		atomic.AddInt32(&dials, 1)
+		return nil, false
+	})
+
+	s.Send(context.Background(), slog.LOG_ERR, "lost")
+	for i := 0; i < 100 && atomic.LoadInt32(&dials) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := s.Close(); err != nil {
+		t.Fatalf("expect sender closed, got: %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expect retry delay interrupted by close, got: %s", d)
+	}
+	if st := s.Stats(); st.Dropped != 1 {
+		t.Errorf("expect 1 dropped record, got: %d", st.Dropped)
+	}
+}
+
+func TestSyslog_failoverPass(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "primary:514", "tag", 8, time.Hour, 8,
+		WithEndpoints(Endpoint{Addr: "backup:514", Priority: 1}),
+		WithFailover(FailoverConfig{MaxFailures: 2, RetryDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	defer s.Close()
+
+	sl := s.(*syslog)
+	var dials int
+	sl.SetDialMethod(func(_ context.Context, protocol, addr, _ string) (SyslogWriter, bool) {
+		dials++
+		return nil, false
+	})
+
+	if _, _, _, err := sl.dialEndpoint(context.Background()); err == nil {
+		t.Errorf("expect error when all endpoints are down")
+	}
+	if dials != 4 {
+		t.Errorf("expect 4 dials in one pass over endpoints, got: %d", dials)
+	}
+}
+
+func TestSyslog_failoverOnWrite(t *testing.T) {
+	batches := make(chan string, 8)
+	s, err := New(context.Background(), SyslogProtocolTCP, "primary:514", "tag", 8, 10*time.Millisecond, 8,
+		WithEndpoints(Endpoint{Addr: "backup:514", Priority: 1}),
+		WithFailover(FailoverConfig{
+			HealthCheckInterval: time.Hour,
+			OnBatch: func(e Endpoint, records int) {
+				batches <- e.String()
+			},
+		}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	defer s.Close()
+
+	backup := &mock.SyslogWriter{}
+	s.(*syslog).SetDialMethod(func(_ context.Context, protocol, addr, _ string) (SyslogWriter, bool) {
+		if addr == "primary:514" {
+			return &failingWriter{}, true
+		}
+		return backup, true
+	})
+
+	waitBatch := func() string {
+		select {
+		case e := <-batches:
+			return e
+		case <-time.After(time.Second):
+			t.Fatalf("batch not sent")
+		}
+		return ""
+	}
+
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	if e := waitBatch(); e != "tcp://backup:514" {
+		t.Errorf("expect batch resent to backup after write failure, got: %s", e)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	if e := waitBatch(); e != "tcp://backup:514" {
+		t.Errorf("expect batch sent to backup, got: %s", e)
+	}
+	if st := s.Stats(); st.Failovers != 1 || st.Dropped != 0 {
+		t.Errorf("expect 1 failover without drops, got: %+v", st)
+	}
+	if c := backup.Messages(slog.LOG_ERR); c != 2 {
+		t.Errorf("expect 2 messages on backup, got: %d", c)
+	}
+}
+
+func TestSyslog_retryOutage(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "primary:514", "tag", 8, 10*time.Millisecond, 8,
+		WithFailover(FailoverConfig{MaxFailures: 2, RetryDelay: time.Millisecond, MaxRetryBackoff: 20 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+
+	var (
+		down  int32 = 1
+		dials int32
+	)
+	mockWriter := &mock.SyslogWriter{}
+	s.(*syslog).SetDialMethod(func(_ context.Context, protocol, addr, _ string) (SyslogWriter, bool) {
+		atomic.AddInt32(&dials, 1)
+		return mockWriter, atomic.LoadInt32(&down) == 0
+	})
+
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	// endpoint is down much longer than MaxFailures*RetryDelay
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&dials); n <= 2 {
+		t.Errorf("expect batch retried, got %d dials", n)
+	}
+	atomic.StoreInt32(&down, 0)
+	for i := 0; i < 100 && mockWriter.Messages(slog.LOG_ERR) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Close()
+
+	if c := mockWriter.Messages(slog.LOG_ERR); c != 2 {
+		t.Errorf("expect 2 messages after outage, got: %d", c)
+	}
+	if st := s.Stats(); st.Dropped != 0 {
+		t.Errorf("expect no dropped records, got: %d", st.Dropped)
+	}
+}
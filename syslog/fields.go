@@ -0,0 +1,32 @@
+package syslog
+
+import (
+	"context"
+
+	"slogger/syslog/format"
+)
+
+type fieldsCtxKey struct{}
+
+// ContextWithFields - returns copy of ctx with fields attached. Fields are merged with
+// fields already attached to ctx, new values override old ones.
+func ContextWithFields(ctx context.Context, fields format.Fields) context.Context {
+	parent := FieldsFromContext(ctx)
+	merged := make(format.Fields, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsCtxKey{}, merged)
+}
+
+// FieldsFromContext - returns fields attached to ctx, nil if none
+func FieldsFromContext(ctx context.Context) format.Fields {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(fieldsCtxKey{}).(format.Fields)
+	return f
+}
@@ -0,0 +1,43 @@
+package file
+
+import (
+	"os"
+	"time"
+)
+
+// SyncPolicy - when written data is flushed to disk with fsync
+type SyncPolicy int
+
+const (
+	// SyncNone - data is flushed by OS (default)
+	SyncNone SyncPolicy = iota
+	// SyncWrite - fsync after every record
+	SyncWrite
+	// SyncClose - fsync after every sender batch (see Writer.Flush) and before file is closed or rotated
+	SyncClose
+)
+
+// DefaultPerm - permissions of created log files
+const DefaultPerm os.FileMode = 0640
+
+// Config - file rotation, retention and durability settings
+type Config struct {
+	// MaxSize - rotate file before it exceeds MaxSize bytes, 0 - no size rotation
+	MaxSize int64
+	// Interval - rotate file when time crosses multiple of Interval (e.g. 24*time.Hour - daily, UTC),
+	// 0 - no time rotation
+	Interval time.Duration
+	// Compress - gzip rotated files (in background, Writer.Close waits for it)
+	Compress bool
+	// MaxBackups - keep at most MaxBackups rotated files, 0 - keep all
+	MaxBackups int
+	// MaxAge - remove rotated files older than MaxAge, 0 - keep all
+	MaxAge time.Duration
+	// Sync - fsync policy
+	Sync SyncPolicy
+	// ReopenOnSIGHUP - reopen file on SIGHUP, for external rotation (logrotate). SIGHUP handler is
+	// installed once per process with the first such writer and stays installed after writers are closed
+	ReopenOnSIGHUP bool
+	// Perm - permissions of created files, DefaultPerm if 0
+	Perm os.FileMode
+}
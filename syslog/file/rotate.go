@@ -0,0 +1,101 @@
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// backupTimeFormat - time suffix of rotated files, sorts in rotation order
+	backupTimeFormat = "20060102T150405.000000000"
+	compressSuffix   = ".gz"
+)
+
+// backupName - returns name of rotated file: "<path>.<time>"
+func backupName(path string, t time.Time) string {
+	return path + "." + t.UTC().Format(backupTimeFormat)
+}
+
+// compressFile - gzips file to file.gz and removes it
+func compressFile(name string, perm os.FileMode) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(name + compressSuffix)
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// backups - returns rotated files of path, oldest first
+func backups(path string) ([]string, error) {
+	names, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, n := range names {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(n, path+"."), compressSuffix)
+		if _, err := time.Parse(backupTimeFormat, suffix); err == nil {
+			res = append(res, n)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// removeBackups - removes rotated files exceeding maxBackups count or older than maxAge
+func removeBackups(path string, maxBackups int, maxAge time.Duration, now time.Time) error {
+	if maxBackups <= 0 && maxAge <= 0 {
+		return nil
+	}
+	names, err := backups(path)
+	if err != nil {
+		return err
+	}
+	for i, n := range names {
+		remove := maxBackups > 0 && i < len(names)-maxBackups
+		if !remove && maxAge > 0 {
+			if fi, err := os.Stat(n); err == nil && now.Sub(fi.ModTime()) > maxAge {
+				remove = true
+			}
+		}
+		if remove {
+			if err := os.Remove(n); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
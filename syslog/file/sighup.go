@@ -0,0 +1,56 @@
+package file
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// SIGHUP is handled once per process: the handler is installed with the first writer with
+// ReopenOnSIGHUP and is never stopped, so signal sent while no writer is open (e.g. sender closed
+// idle connection) does not terminate process
+var (
+	hupOnce    sync.Once
+	hupMu      sync.Mutex
+	hupWriters = make(map[*Writer]struct{})
+)
+
+// registerSIGHUP - makes w reopen its file on SIGHUP, installs handler on first call
+func registerSIGHUP(w *Writer) {
+	hupMu.Lock()
+	hupWriters[w] = struct{}{}
+	hupMu.Unlock()
+
+	hupOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGHUP)
+		go func() {
+			for range signals {
+				reopenRegistered()
+			}
+		}()
+	})
+}
+
+// unregisterSIGHUP - stops reopening of w file on SIGHUP, handler stays installed
+func unregisterSIGHUP(w *Writer) {
+	hupMu.Lock()
+	defer hupMu.Unlock()
+
+	delete(hupWriters, w)
+}
+
+// reopenRegistered - reopens files of registered writers
+func reopenRegistered() {
+	hupMu.Lock()
+	writers := make([]*Writer, 0, len(hupWriters))
+	for w := range hupWriters {
+		writers = append(writers, w)
+	}
+	hupMu.Unlock()
+
+	for _, w := range writers {
+		w.Reopen()
+	}
+}
@@ -0,0 +1,324 @@
+package file
+
+import (
+	"errors"
+	"log"
+	"log/syslog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+const (
+	severityMask = 0x07
+	facilityMask = 0xf8
+)
+
+// Writer - writes records as lines to local file with size/time rotation, compression and
+// retention of rotated files. It implements syslog.SyslogWriter and syslog.RecordWriter,
+// so can be used as sender sink
+type Writer struct {
+	priority  syslog.Priority
+	tag       string
+	hostname  string
+	path      string
+	cfg       Config
+	formatter format.Formatter
+	now       func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// openedAt - time of first line of file, base of Interval rotation
+	openedAt time.Time
+	closed   bool
+
+	// compressing - background compression and retention of rotated files
+	compressing sync.WaitGroup
+	compressMu  sync.Mutex
+}
+
+// Open - opens (appends to) file at path
+func Open(path string, priority syslog.Priority, tag string, cfg Config) (*Writer, error) {
+	if path == "" {
+		return nil, errors.New("file: empty path")
+	}
+	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
+		return nil, errors.New("file: invalid priority")
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	if cfg.Perm == 0 {
+		cfg.Perm = DefaultPerm
+	}
+	hostname, _ := os.Hostname()
+
+	w := &Writer{
+		priority:  priority,
+		tag:       tag,
+		hostname:  hostname,
+		path:      path,
+		cfg:       cfg,
+		formatter: format.Default,
+		now:       time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if cfg.ReopenOnSIGHUP {
+		registerSIGHUP(w)
+	}
+	return w, nil
+}
+
+// SetFormatter - set formatter used to build line from record, format.Default if nil
+func (w *Writer) SetFormatter(f format.Formatter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if f == nil {
+		f = format.Default
+	}
+	w.formatter = f
+}
+
+// open opens file for appending. Time of existing file is its modification time.
+// It must be called with w.mu held or before writer is shared.
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.cfg.Perm)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = fi.Size()
+	w.openedAt = fi.ModTime()
+	return nil
+}
+
+// closeFile syncs (by policy) and closes file.
+// It must be called with w.mu held.
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	var err error
+	if w.cfg.Sync == SyncClose {
+		err = w.file.Sync()
+	}
+	if cErr := w.file.Close(); err == nil {
+		err = cErr
+	}
+	w.file = nil
+	return err
+}
+
+// Reopen - reopens file by path, e.g. after it was moved by external rotation. New file is
+// opened before old one is closed, so records are not lost if reopening fails
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	old := w.file
+	w.file = nil
+	if err := w.open(); err != nil {
+		w.file = old
+		return err
+	}
+	if old != nil {
+		if w.cfg.Sync == SyncClose {
+			old.Sync()
+		}
+		return old.Close()
+	}
+	return nil
+}
+
+// Rotate - renames current file to "<path>.<time>" (gzipped if Compress is set), opens new
+// file and removes rotated files by retention settings. With Compress, compression and retention
+// run in background, so writes are not blocked by them
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rotate()
+}
+
+// rotate - see Rotate.
+// It must be called with w.mu held.
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	now := w.now()
+	backup := backupName(w.path, now)
+	if err := os.Rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	if w.cfg.Compress {
+		w.compressing.Add(1)
+		go w.compress(backup, now)
+		return nil
+	}
+	return removeBackups(w.path, w.cfg.MaxBackups, w.cfg.MaxAge, now)
+}
+
+// compress - gzips rotated file and removes rotated files by retention settings
+func (w *Writer) compress(backup string, now time.Time) {
+	defer w.compressing.Done()
+	w.compressMu.Lock()
+	defer w.compressMu.Unlock()
+
+	if err := compressFile(backup, w.cfg.Perm); err != nil {
+		log.Printf("file: cannot compress %s: %v", backup, err)
+	}
+	if err := removeBackups(w.path, w.cfg.MaxBackups, w.cfg.MaxAge, now); err != nil {
+		log.Printf("file: cannot remove rotated files of %s: %v", w.path, err)
+	}
+}
+
+// needRotate - returns true if line of n bytes must be written to new file.
+// It must be called with w.mu held.
+func (w *Writer) needRotate(n int, now time.Time) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.cfg.MaxSize > 0 && w.size+int64(n) > w.cfg.MaxSize {
+		return true
+	}
+	if w.cfg.Interval > 0 && now.Truncate(w.cfg.Interval).After(w.openedAt.Truncate(w.cfg.Interval)) {
+		return true
+	}
+	return false
+}
+
+// Close - closes file, stops reopening it on SIGHUP and waits for background compression
+func (w *Writer) Close() error {
+	if w.cfg.ReopenOnSIGHUP {
+		unregisterSIGHUP(w)
+	}
+
+	w.mu.Lock()
+	w.closed = true
+	err := w.closeFile()
+	w.mu.Unlock()
+
+	w.compressing.Wait()
+	return err
+}
+
+// Flush - fsyncs file with SyncClose policy, sender calls it after every batch
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || w.cfg.Sync != SyncClose {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.writeRecord(&format.Record{Priority: w.priority, Message: string(b)})
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// WriteRecord - formats record and writes it as a line
+func (w *Writer) WriteRecord(r *format.Record) error {
+	_, err := w.writeRecord(r)
+	return err
+}
+
+func (w *Writer) writeRecord(r *format.Record) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	rec := *r
+	rec.Priority = (w.priority & facilityMask) | (r.Priority & severityMask)
+	if rec.Tag == "" {
+		rec.Tag = w.tag
+	}
+	if rec.PID == 0 {
+		rec.PID = os.Getpid()
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = now
+	}
+	if rec.Hostname == "" {
+		rec.Hostname = w.hostname
+	}
+
+	line := strings.TrimSuffix(w.formatter.Format(&rec), "\n") + "\n"
+	if w.file == nil {
+		// previous rotation failed to open new file
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.needRotate(len(line), now) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size == 0 {
+		// time period of file starts with its first line
+		w.openedAt = now
+	}
+	n, err := w.file.WriteString(line)
+	w.size += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	if w.cfg.Sync == SyncWrite {
+		if err := w.file.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	return len(r.Message), nil
+}
@@ -0,0 +1,212 @@
+package file
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "slogger")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	return dir
+}
+
+func readFile(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("cannot read file: %v", err)
+	}
+	return string(b)
+}
+
+// clock - returns now func incremented by step on every call
+func clock(start time.Time, step time.Duration) func() time.Time {
+	t := start
+	return func() time.Time {
+		t = t.Add(step)
+		return t
+	}
+}
+
+func TestWriter_Write(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	w, err := Open(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{Sync: SyncWrite})
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	w.SetFormatter(format.Raw{})
+	w.Err("first")
+	w.Info("second\n")
+	w.Close()
+
+	if s := readFile(t, path); s != "first\nsecond\n" {
+		t.Errorf("unexpected file content: %q", s)
+	}
+}
+
+func TestWriter_RotateSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	w, err := Open(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	w.now = clock(time.Now(), time.Millisecond)
+	w.SetFormatter(format.Raw{})
+	for _, m := range []string{"line-1", "line-2", "line-3", "line-4"} {
+		if err := w.Err(m); err != nil {
+			t.Fatalf("expect no error, got: %v", err)
+		}
+	}
+	w.Close()
+
+	if s := readFile(t, path); s != "line-4\n" {
+		t.Errorf("unexpected file content: %q", s)
+	}
+	names, _ := backups(path)
+	if len(names) != 2 {
+		t.Fatalf("expect 2 rotated files, got: %v", names)
+	}
+	if s := readFile(t, names[0]); s != "line-2\n" {
+		t.Errorf("unexpected rotated file content: %q", s)
+	}
+	if s := readFile(t, names[1]); s != "line-3\n" {
+		t.Errorf("unexpected rotated file content: %q", s)
+	}
+}
+
+func TestWriter_RotateInterval(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	w, err := Open(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{Interval: time.Hour, Compress: true})
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	w.SetFormatter(format.Raw{})
+	w.now = clock(time.Date(2019, 7, 1, 10, 0, 0, 0, time.UTC), 20*time.Minute)
+	w.Err("first")  // 10:20
+	w.Err("second") // 10:40
+	w.Err("third")  // 11:00 - new hour
+	w.Close()
+
+	if s := readFile(t, path); s != "third\n" {
+		t.Errorf("unexpected file content: %q", s)
+	}
+	names, _ := backups(path)
+	if len(names) != 1 || !strings.HasSuffix(names[0], compressSuffix) {
+		t.Fatalf("expect 1 compressed rotated file, got: %v", names)
+	}
+	f, err := os.Open(names[0])
+	if err != nil {
+		t.Fatalf("cannot open rotated file: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("cannot read gzip: %v", err)
+	}
+	b, _ := ioutil.ReadAll(zr)
+	if string(b) != "first\nsecond\n" {
+		t.Errorf("unexpected rotated file content: %q", b)
+	}
+}
+
+func TestWriter_MaxAge(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	old := backupName(path, time.Now().Add(-48*time.Hour))
+	if err := ioutil.WriteFile(old, []byte("old\n"), 0640); err != nil {
+		t.Fatalf("cannot write file: %v", err)
+	}
+	mtime := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(old, mtime, mtime)
+
+	w, err := Open(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	w.Err("message")
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	w.Close()
+
+	names, _ := backups(path)
+	if len(names) != 1 || names[0] == old {
+		t.Errorf("expect old file removed, got: %v", names)
+	}
+}
+
+func TestWriter_ReopenOnSIGHUP(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	w, err := Open(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{ReopenOnSIGHUP: true})
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	defer w.Close()
+	w.SetFormatter(format.Raw{})
+	w.Err("before")
+
+	// external rotation
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("cannot rename: %v", err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Err("after")
+
+	if s := readFile(t, path+".1"); s != "before\n" {
+		t.Errorf("unexpected rotated file content: %q", s)
+	}
+	if s := readFile(t, path); s != "after\n" {
+		t.Errorf("unexpected file content: %q", s)
+	}
+}
+
+func TestWriter_SIGHUPAfterClose(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	w, err := Open(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", Config{ReopenOnSIGHUP: true})
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	w.Close()
+	os.Remove(path)
+
+	// handler stays installed, signal does not terminate process and closed writer is not reopened
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expect closed writer not reopened, got: %v", err)
+	}
+}
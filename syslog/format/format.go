@@ -0,0 +1,92 @@
+package format
+
+import (
+	"errors"
+	"log/syslog"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Fields - structured data attached to record
+type Fields map[string]interface{}
+
+// Record - a single syslog record passed to Formatter
+type Record struct {
+	Priority  syslog.Priority
+	Timestamp time.Time
+	Hostname  string
+	Tag       string
+	PID       int
+	// ProcID - PROCID sent instead of PID if set (e.g. container ID)
+	ProcID  string
+	Message string
+
+	// Caller - "file:line" of the code which sent the record, empty if not collected
+	Caller string
+	// Fields - structured data of the record, may be nil
+	Fields Fields
+	// TraceID, SpanID - hex trace context of the record, empty if not set
+	TraceID string
+	SpanID  string
+}
+
+// ErrDropped - wrapped by errors of writers which cannot send record at all (e.g. cannot encode it,
+// or it exceeds protocol size). Connection is fine, so sender drops such record instead of resending it
+var ErrDropped = errors.New("record dropped")
+
+// Formatter - builds syslog wire representation (without transport framing) of record
+type Formatter interface {
+	Format(r *Record) string
+}
+
+// FormatterFunc - adapter to use ordinary functions as Formatter
+type FormatterFunc func(r *Record) string
+
+// Format - calls f(r)
+func (f FormatterFunc) Format(r *Record) string {
+	return f(r)
+}
+
+// Default - formatter used by writers if no formatter was set
+var Default Formatter = RFC3164{}
+
+var severityNames = [...]string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// SeverityName - returns syslog keyword of priority severity (e.g. "err" for LOG_ERR)
+func SeverityName(p syslog.Priority) string {
+	return severityNames[p&0x07]
+}
+
+// TruncateUTF8 - returns prefix of s of at most n bytes without breaking multibyte characters
+func TruncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// procID - returns ProcID of record or PID if ProcID is empty, NILVALUE if no one is set
+func procID(r *Record) string {
+	if r.ProcID != "" {
+		return r.ProcID
+	}
+	if r.PID > 0 {
+		return strconv.Itoa(r.PID)
+	}
+	return "-"
+}
+
+// nilValue - returns RFC 5424 NILVALUE for empty strings
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package format
+
+import (
+	"log/syslog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRecord() *Record {
+	return &Record{
+		Priority:  syslog.LOG_ERR | syslog.LOG_DAEMON,
+		Timestamp: time.Date(2019, time.August, 3, 7, 8, 9, 123456789, time.UTC),
+		Hostname:  "host",
+		Tag:       "tag",
+		PID:       42,
+		Message:   "Test message\n",
+	}
+}
+
+func TestRFC3164(t *testing.T) {
+	expect := "<27>Aug  3 07:08:09 host tag[42]: Test message"
+	if m := (RFC3164{}).Format(testRecord()); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestRFC5424(t *testing.T) {
+	expect := "<27>1 2019-08-03T07:08:09.123456Z host tag 42 - - Test message"
+	if m := (RFC5424{}).Format(testRecord()); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+
+	r := testRecord()
+	r.Hostname, r.Tag, r.PID, r.Timestamp = "", "", 0, time.Time{}
+	expect = "<27>1 - - - - ID47 - Test message"
+	if m := (RFC5424{MsgID: "ID47"}).Format(r); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestRFC5424_Header(t *testing.T) {
+	r := testRecord()
+	r.Hostname, r.Tag, r.ProcID = "host name\n", "прил", strings.Repeat("p", MaxProcIDLen+1)
+	expect := "<27>1 2019-08-03T07:08:09.123456Z host_name_ ________ " + strings.Repeat("p", MaxProcIDLen) +
+		" ID_47 - Test message"
+	if m := (RFC5424{MsgID: "ID 47"}).Format(r); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	expect := "Test message"
+	if m := (Raw{}).Format(testRecord()); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestFormatterFunc(t *testing.T) {
+	f := FormatterFunc(func(r *Record) string {
+		return r.Tag + ": " + r.Message
+	})
+	expect := "tag: Test message\n"
+	if m := f.Format(testRecord()); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestRFC5424_StructuredData(t *testing.T) {
+	r := testRecord()
+	r.Timestamp = r.Timestamp.Truncate(time.Millisecond)
+	r.Fields = Fields{"request id": "a\"b]c\\", "count": 3}
+
+	expect := `<27>1 2019-08-03T07:08:09.123Z host tag 42 - [meta@32473 count="3" request_id="a\"b\]c\\"] Test message`
+	if m := (RFC5424{SDID: "meta@32473"}).Format(r); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+
+	expect = `<27>1 2019-08-03T07:08:09.123Z host tag 42 - - Test message`
+	if m := (RFC5424{}).Format(r); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	for _, tc := range []struct {
+		s      string
+		n      int
+		expect string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 2, "ab"},
+		{"aяb", 2, "a"},
+		{"яb", 1, ""},
+		{"abc", -1, ""},
+		{"", 0, ""},
+	} {
+		if r := TruncateUTF8(tc.s, tc.n); r != tc.expect {
+			t.Errorf("TruncateUTF8(%q, %d): expect %q, got %q", tc.s, tc.n, tc.expect, r)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ceeCookie - prefix expected by rsyslog mmjsonparse
+const ceeCookie = "@cee: "
+
+// JSON keys reserved for record attributes, fields with the same names are ignored
+const (
+	JSONKeyMessage   = "msg"
+	JSONKeySeverity  = "severity"
+	JSONKeyTag       = "tag"
+	JSONKeyTimestamp = "ts"
+	JSONKeyCaller    = "caller"
+	JSONKeyTruncated = "truncated"
+)
+
+// JSON - encodes record (message, severity, tag, timestamp, caller and fields) as JSON object
+// and sends it as MSG part of syslog message, formatted by Header.
+type JSON struct {
+	// Header - formatter of syslog envelope, Default if nil
+	Header Formatter
+	// CEE - prefix JSON with "@cee: " cookie for rsyslog mmjsonparse
+	CEE bool
+	// MaxMessageSize - maximum size of "msg" value in bytes, 0 - no limit.
+	// Longer messages are cut on UTF-8 boundary and "truncated" key is set
+	MaxMessageSize int
+}
+
+// Format - implements Formatter
+func (f JSON) Format(r *Record) string {
+	header := f.Header
+	if header == nil {
+		header = Default
+	}
+
+	envelope := *r
+	envelope.Message = f.Encode(r)
+	return header.Format(&envelope)
+}
+
+// Encode - returns JSON payload (with CEE cookie if set) of record
+func (f JSON) Encode(r *Record) string {
+	msg := strings.TrimSuffix(r.Message, "\n")
+	truncated := false
+	if f.MaxMessageSize > 0 && len(msg) > f.MaxMessageSize {
+		msg = TruncateUTF8(msg, f.MaxMessageSize)
+		truncated = true
+	}
+
+	obj := make(map[string]interface{}, len(r.Fields)+6)
+	for k, v := range r.Fields {
+		obj[k] = jsonValue(v)
+	}
+	obj[JSONKeyMessage] = msg
+	obj[JSONKeySeverity] = SeverityName(r.Priority)
+	obj[JSONKeyTag] = r.Tag
+	if !r.Timestamp.IsZero() {
+		obj[JSONKeyTimestamp] = r.Timestamp.Format(time.RFC3339Nano)
+	}
+	if r.Caller != "" {
+		obj[JSONKeyCaller] = r.Caller
+	}
+	if truncated {
+		obj[JSONKeyTruncated] = true
+	}
+
+	b := new(bytes.Buffer)
+	if f.CEE {
+		b.WriteString(ceeCookie)
+	}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		// can not happen: all values are checked by jsonValue
+		return msg
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// jsonValue - returns v if it can be marshaled to JSON, string representation otherwise
+func jsonValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case error:
+		return vv.Error()
+	case fmt.Stringer:
+		return vv.String()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package format
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSON_Encode(t *testing.T) {
+	r := testRecord()
+	r.Message = "quote \" newline \n tab \t <b>"
+	r.Caller = "file.go:12"
+	r.Fields = Fields{"request_id": "abc", "count": 3, "msg": "ignored"}
+
+	p := JSON{}.Encode(r)
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(p), &obj); err != nil {
+		t.Fatalf("expect valid JSON, got %q: %v", p, err)
+	}
+	expect := map[string]interface{}{
+		"msg":        r.Message,
+		"severity":   "err",
+		"tag":        "tag",
+		"ts":         "2019-08-03T07:08:09.123456789Z",
+		"caller":     "file.go:12",
+		"request_id": "abc",
+		"count":      float64(3),
+	}
+	if len(obj) != len(expect) {
+		t.Errorf("expect %d keys, got %d: %v", len(expect), len(obj), obj)
+	}
+	for k, v := range expect {
+		if obj[k] != v {
+			t.Errorf("key %s: expect %v, got %v", k, v, obj[k])
+		}
+	}
+	if strings.Contains(p, "\\u003c") {
+		t.Errorf("expect HTML characters not escaped, got %q", p)
+	}
+}
+
+func TestJSON_CEE(t *testing.T) {
+	m := JSON{CEE: true}.Format(testRecord())
+	prefix := "<27>Aug  3 07:08:09 host tag[42]: @cee: {"
+	if !strings.HasPrefix(m, prefix) {
+		t.Errorf("expect prefix %q, got %q", prefix, m)
+	}
+	if strings.Contains(m, "\n") {
+		t.Errorf("expect single line, got %q", m)
+	}
+}
+
+func TestJSON_MaxMessageSize(t *testing.T) {
+	r := testRecord()
+	r.Message = "abécd"
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(JSON{MaxMessageSize: 3}.Encode(r)), &obj); err != nil {
+		t.Fatalf("expect valid JSON: %v", err)
+	}
+	if obj["msg"] != "ab" {
+		t.Errorf("expect msg %q, got %q", "ab", obj["msg"])
+	}
+	if obj["truncated"] != true {
+		t.Errorf("expect truncated flag, got %v", obj["truncated"])
+	}
+}
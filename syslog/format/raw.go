@@ -0,0 +1,11 @@
+package format
+
+import "strings"
+
+// Raw - sends message as is, without syslog header
+type Raw struct{}
+
+// Format - implements Formatter
+func (Raw) Format(r *Record) string {
+	return strings.TrimSuffix(r.Message, "\n")
+}
@@ -0,0 +1,25 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rfc3164Timestamp - strict RFC 3164 TIMESTAMP: "Mmm dd hh:mm:ss", day padded with space
+const rfc3164Timestamp = "Jan _2 15:04:05"
+
+// RFC3164 - BSD syslog format: "<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG".
+// HOSTNAME is omitted if empty, as local syslog sockets expect
+type RFC3164 struct{}
+
+// Format - implements Formatter
+func (RFC3164) Format(r *Record) string {
+	msg := strings.TrimSuffix(r.Message, "\n")
+	if r.Hostname == "" {
+		return fmt.Sprintf("<%d>%s %s[%s]: %s",
+			r.Priority, r.Timestamp.Format(rfc3164Timestamp), r.Tag, procID(r), msg)
+	}
+	return fmt.Sprintf("<%d>%s %s %s[%s]: %s",
+		r.Priority, r.Timestamp.Format(rfc3164Timestamp), r.Hostname,
+		r.Tag, procID(r), msg)
+}
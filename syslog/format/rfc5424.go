@@ -0,0 +1,93 @@
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	rfc5424Version = 1
+	// rfc5424Timestamp - RFC 5424 allows at most 6 digits of second fraction,
+	// trailing zeros are omitted, so precision follows record timestamp
+	rfc5424Timestamp = "2006-01-02T15:04:05.999999Z07:00"
+	// sdNameMaxLen - maximum length of SD-NAME (PARAM-NAME)
+	sdNameMaxLen = 32
+	// bom - UTF-8 byte order mark, marks MSG as UTF-8 encoded
+	bom = "\xEF\xBB\xBF"
+)
+
+var sdParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// RFC5424 - IETF syslog format: "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG"
+type RFC5424 struct {
+	// MsgID - MSGID field, NILVALUE if empty
+	MsgID string
+	// SDID - if set, record fields are sent as STRUCTURED-DATA element with this ID
+	// (e.g. "meta@32473"), NILVALUE is sent otherwise
+	SDID string
+	// BOM - start MSG with UTF-8 BOM, as RFC 5424 requires for UTF-8 messages
+	BOM bool
+}
+
+// Format - implements Formatter
+func (f RFC5424) Format(r *Record) string {
+	msg := strings.TrimSuffix(r.Message, "\n")
+	if f.BOM && msg != "" {
+		msg = bom + msg
+	}
+
+	ts := "-"
+	if !r.Timestamp.IsZero() {
+		ts = r.Timestamp.Format(rfc5424Timestamp)
+	}
+	return fmt.Sprintf("<%d>%d %s %s %s %s %s %s %s",
+		r.Priority, rfc5424Version, ts, headerField(r.Hostname, MaxHostnameLen), headerField(r.Tag, MaxTagLen),
+		headerField(procID(r), MaxProcIDLen), headerField(f.MsgID, MaxMsgIDLen), f.structuredData(r.Fields), msg)
+}
+
+// headerField - returns NILVALUE for empty v, v with characters other than PRINTUSASCII replaced
+// with '_' and cut to max length otherwise, so header fields cannot break record
+func headerField(v string, max int) string {
+	return nilValue(printUSASCII(v, max, true))
+}
+
+// structuredData - returns SD-ELEMENT with fields as params, NILVALUE if no fields or SDID
+func (f RFC5424) structuredData(fields Fields) string {
+	if f.SDID == "" || len(fields) == 0 {
+		return "-"
+	}
+
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	b := new(strings.Builder)
+	b.WriteByte('[')
+	b.WriteString(sdName(f.SDID))
+	for _, k := range names {
+		b.WriteByte(' ')
+		b.WriteString(sdName(k))
+		b.WriteString(`="`)
+		b.WriteString(sdParamEscaper.Replace(fmt.Sprint(fields[k])))
+		b.WriteByte('"')
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// sdName - replaces characters not allowed in SD-NAME with '_' and cuts it to 32 characters
+func sdName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > sdNameMaxLen {
+		b = b[:sdNameMaxLen]
+	}
+	return string(b)
+}
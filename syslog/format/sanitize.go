@@ -0,0 +1,196 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidUTF8Policy - what Sanitize does with invalid UTF-8 sequences
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Replace - replace invalid bytes with U+FFFD
+	InvalidUTF8Replace InvalidUTF8Policy = iota
+	// InvalidUTF8Escape - escape invalid bytes rsyslog style ("#377")
+	InvalidUTF8Escape
+	// InvalidUTF8Keep - send invalid bytes as is
+	InvalidUTF8Keep
+)
+
+// ControlCharsPolicy - what Sanitize does with control characters (including LF) in message
+type ControlCharsPolicy int
+
+const (
+	// ControlCharsEscape - escape control characters rsyslog style ("#012" for LF)
+	ControlCharsEscape ControlCharsPolicy = iota
+	// ControlCharsSpace - replace control characters with space
+	ControlCharsSpace
+	// ControlCharsStrip - remove control characters
+	ControlCharsStrip
+	// ControlCharsKeep - send control characters as is
+	ControlCharsKeep
+)
+
+// HeaderPolicy - what Sanitize does with characters not allowed in TAG and HOSTNAME
+type HeaderPolicy int
+
+const (
+	// HeaderReplace - replace not allowed characters with '_'
+	HeaderReplace HeaderPolicy = iota
+	// HeaderStrip - remove not allowed characters
+	HeaderStrip
+	// HeaderKeep - send TAG and HOSTNAME as is (RFC5424 still replaces characters not allowed in its header)
+	HeaderKeep
+)
+
+// Maximum lengths of header fields (RFC 5424 APP-NAME, HOSTNAME, PROCID and MSGID)
+const (
+	MaxTagLen      = 48
+	MaxHostnameLen = 255
+	MaxProcIDLen   = 128
+	MaxMsgIDLen    = 32
+)
+
+// Sanitize - formatter which cleans record before formatting it with Formatter, so user
+// controlled strings cannot forge syslog records. Zero value uses the safest policies
+type Sanitize struct {
+	// Formatter - formatter of sanitized record, Default if nil
+	Formatter Formatter
+
+	InvalidUTF8  InvalidUTF8Policy
+	ControlChars ControlCharsPolicy
+	Header       HeaderPolicy
+	// KeepTab - do not apply ControlChars policy to horizontal tab
+	KeepTab bool
+}
+
+// Format - implements Formatter
+func (s Sanitize) Format(r *Record) string {
+	f := s.Formatter
+	if f == nil {
+		f = Default
+	}
+
+	rec := *r
+	rec.Message = s.Message(strings.TrimSuffix(r.Message, "\n"))
+	if s.Header != HeaderKeep {
+		rec.Tag = s.header(r.Tag, MaxTagLen)
+		rec.Hostname = s.header(r.Hostname, MaxHostnameLen)
+		rec.ProcID = s.header(r.ProcID, MaxProcIDLen)
+	}
+	return f.Format(&rec)
+}
+
+// Message - returns message cleaned according to InvalidUTF8 and ControlChars policies
+func (s Sanitize) Message(m string) string {
+	if s.clean(m) {
+		return m
+	}
+
+	b := new(strings.Builder)
+	b.Grow(len(m))
+	for i := 0; i < len(m); {
+		c, size := utf8.DecodeRuneInString(m[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			switch s.InvalidUTF8 {
+			case InvalidUTF8Replace:
+				b.WriteRune(utf8.RuneError)
+			case InvalidUTF8Escape:
+				b.WriteString(escapeByte(m[i]))
+			default:
+				b.WriteByte(m[i])
+			}
+		case isControl(c) && !(c == '\t' && s.KeepTab):
+			switch s.ControlChars {
+			case ControlCharsEscape:
+				b.WriteString(escapeByte(byte(c)))
+			case ControlCharsSpace:
+				b.WriteByte(' ')
+			case ControlCharsStrip:
+			default:
+				b.WriteRune(c)
+			}
+		default:
+			b.WriteString(m[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// clean - returns true if m needs no changes
+func (s Sanitize) clean(m string) bool {
+	for i := 0; i < len(m); {
+		c := m[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(m[i:])
+			if r == utf8.RuneError && size == 1 && s.InvalidUTF8 != InvalidUTF8Keep {
+				return false
+			}
+			i += size
+			continue
+		}
+		if isControl(rune(c)) && !(c == '\t' && s.KeepTab) && s.ControlChars != ControlCharsKeep {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// header - returns v with only printable US-ASCII characters, cut to max length
+func (s Sanitize) header(v string, max int) string {
+	return printUSASCII(v, max, s.Header == HeaderReplace)
+}
+
+// printUSASCII - returns v with only RFC 5424 PRINTUSASCII characters (33-126), others are
+// replaced with '_' or removed, cut to max length
+func printUSASCII(v string, max int, replace bool) string {
+	b := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c > ' ' && c < 127 {
+			b = append(b, c)
+		} else if replace {
+			b = append(b, '_')
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// ValidateTag - returns error if tag has characters not allowed in TAG (APP-NAME) or is too long
+func ValidateTag(tag string) error {
+	return validateHeader("tag", tag, MaxTagLen)
+}
+
+// ValidateHostname - returns error if hostname has characters not allowed in HOSTNAME or is too long
+func ValidateHostname(hostname string) error {
+	return validateHeader("hostname", hostname, MaxHostnameLen)
+}
+
+func validateHeader(name, v string, max int) error {
+	if len(v) > max {
+		return fmt.Errorf("%s is longer than %d characters", name, max)
+	}
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c <= ' ' || c >= 127 {
+			return fmt.Errorf("%s has not allowed character %q at %d", name, c, i)
+		}
+	}
+	return nil
+}
+
+// isControl - returns true for C0 control characters and DEL
+func isControl(c rune) bool {
+	return c < ' ' || c == 0x7f
+}
+
+// escapeByte - rsyslog escape sequence of byte: '#' and 3 octal digits
+func escapeByte(c byte) string {
+	return fmt.Sprintf("#%03o", c)
+}
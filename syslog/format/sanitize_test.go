@@ -0,0 +1,78 @@
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize_Message(t *testing.T) {
+	forged := "login failed\n<13>Aug  3 07:08:09 host tag[1]: forged"
+	for _, tc := range []struct {
+		name   string
+		s      Sanitize
+		m      string
+		expect string
+	}{
+		{"escape", Sanitize{}, forged, "login failed#012<13>Aug  3 07:08:09 host tag[1]: forged"},
+		{"space", Sanitize{ControlChars: ControlCharsSpace}, "a\r\nb", "a  b"},
+		{"strip", Sanitize{ControlChars: ControlCharsStrip}, "a\x00b\x7fc", "abc"},
+		{"keep", Sanitize{ControlChars: ControlCharsKeep}, "a\nb", "a\nb"},
+		{"tab", Sanitize{KeepTab: true}, "a\tb\x1b", "a\tb#033"},
+		{"clean", Sanitize{}, "привет, world", "привет, world"},
+		{"utf8 replace", Sanitize{}, "a\xffb\xc3", "a�b�"},
+		{"utf8 escape", Sanitize{InvalidUTF8: InvalidUTF8Escape}, "a\xffbя", "a#377bя"},
+		{"utf8 keep", Sanitize{InvalidUTF8: InvalidUTF8Keep}, "a\xff\n", "a\xff#012"},
+		{"utf8 after valid", Sanitize{}, "яя\xe2\x82", "яя��"},
+		{"long clean", Sanitize{}, strings.Repeat("я", 1<<16), strings.Repeat("я", 1<<16)},
+	} {
+		if m := tc.s.Message(tc.m); m != tc.expect {
+			t.Errorf("%s: expect %q, got %q", tc.name, tc.expect, m)
+		}
+	}
+}
+
+func TestSanitize_Format(t *testing.T) {
+	r := testRecord()
+	r.Message = "first\nsecond\n"
+	r.Tag = "my tag]"
+	r.Hostname = "host\nname"
+
+	expect := "<27>Aug  3 07:08:09 host_name my_tag][42]: first#012second"
+	if m := (Sanitize{}).Format(r); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+
+	expect = "<27>Aug  3 07:08:09 hostname mytag][42]: first#012second"
+	if m := (Sanitize{Header: HeaderStrip}).Format(r); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+
+	r.Tag = strings.Repeat("t", MaxTagLen+10)
+	if m := (Sanitize{}).Format(r); !strings.Contains(m, " "+strings.Repeat("t", MaxTagLen)+"[") {
+		t.Errorf("expect tag cut to %d characters, got %q", MaxTagLen, m)
+	}
+}
+
+func TestRFC5424_BOM(t *testing.T) {
+	expect := "<27>1 2019-08-03T07:08:09.123456Z host tag 42 - - \xEF\xBB\xBFTest message"
+	if m := (Sanitize{Formatter: RFC5424{BOM: true}}).Format(testRecord()); m != expect {
+		t.Errorf("expect %q, got %q", expect, m)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := ValidateTag("my-app"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	for _, tag := range []string{"my app", "app\n", "прил", strings.Repeat("t", MaxTagLen+1)} {
+		if err := ValidateTag(tag); err == nil {
+			t.Errorf("tag %q: expect error, got no error", tag)
+		}
+	}
+	if err := ValidateHostname("host.example.com"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	if err := ValidateHostname("host name"); err == nil {
+		t.Errorf("expect error, got no error")
+	}
+}
@@ -0,0 +1,289 @@
+// Package forwardtest provides in-process Fluentd Forward server for tests
+package forwardtest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"slogger/syslog/forward/msgpack"
+)
+
+// Hostname - server hostname sent in PONG
+const Hostname = "forwardtest"
+
+// Event - received event
+type Event struct {
+	Tag    string
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+// Server - Forward server which stores all events and acknowledges chunks.
+// It accepts Message, Forward and PackedForward modes
+type Server struct {
+	Addr string
+
+	sharedKey string
+	ln        net.Listener
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	events    []Event
+	chunks    []string
+	dropAcks  int
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewServer - starts server on random local tcp port. If sharedKey is not empty,
+// clients must pass shared key handshake
+func NewServer(sharedKey string) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return Serve(ln, sharedKey), nil
+}
+
+// Serve - starts server on listener ln (e.g. unix socket listener)
+func Serve(ln net.Listener, sharedKey string) *Server {
+	s := &Server{
+		Addr:      ln.Addr().String(),
+		sharedKey: sharedKey,
+		ln:        ln,
+		conns:     make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// Events - returns received events
+func (s *Server) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Event(nil), s.events...)
+}
+
+// Chunks - returns chunk IDs of received messages in order
+func (s *Server) Chunks() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.chunks...)
+}
+
+// DropAcks - events of next n chunks are stored, but not acknowledged
+func (s *Server) DropAcks(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropAcks = n
+}
+
+// Close - stops server and closes all connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	err := s.ln.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	dec := msgpack.NewDecoder(bufio.NewReader(c))
+	if s.sharedKey != "" && !s.handshake(c, dec) {
+		return
+	}
+	for {
+		v, err := dec.Decode()
+		if err != nil {
+			return
+		}
+		msg, ok := v.([]interface{})
+		if !ok || len(msg) < 2 {
+			return
+		}
+		events, option, err := decodeMessage(msg)
+		if err != nil {
+			return
+		}
+
+		chunk, _ := option["chunk"].(string)
+		s.mu.Lock()
+		s.events = append(s.events, events...)
+		ack := chunk != ""
+		if ack {
+			s.chunks = append(s.chunks, chunk)
+			if s.dropAcks > 0 {
+				s.dropAcks--
+				ack = false
+			}
+		}
+		s.mu.Unlock()
+
+		if ack {
+			var e msgpack.Encoder
+			e.Encode(map[string]interface{}{"ack": chunk})
+			if _, err := c.Write(e.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// decodeMessage - decodes events of Message, Forward or PackedForward mode
+func decodeMessage(msg []interface{}) ([]Event, map[string]interface{}, error) {
+	tag, _ := msg[0].(string)
+	option := func(i int) map[string]interface{} {
+		if len(msg) > i {
+			m, _ := msg[i].(map[string]interface{})
+			return m
+		}
+		return nil
+	}
+
+	switch entries := msg[1].(type) {
+	case []interface{}:
+		var events []Event
+		for _, e := range entries {
+			if ev, ok := decodeEntry(tag, e); ok {
+				events = append(events, ev)
+			}
+		}
+		return events, option(2), nil
+	case []byte, string:
+		var b []byte
+		if s, ok := entries.(string); ok {
+			b = []byte(s)
+		} else {
+			b = entries.([]byte)
+		}
+		dec := msgpack.NewDecoder(bytes.NewReader(b))
+		var events []Event
+		for {
+			e, err := dec.Decode()
+			if err == io.EOF {
+				return events, option(2), nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if ev, ok := decodeEntry(tag, e); ok {
+				events = append(events, ev)
+			}
+		}
+	default:
+		ev, _ := decodeEntry(tag, msg[1:])
+		return []Event{ev}, option(3), nil
+	}
+}
+
+// decodeEntry - decodes [time, record] entry
+func decodeEntry(tag string, v interface{}) (Event, bool) {
+	e, ok := v.([]interface{})
+	if !ok || len(e) < 2 {
+		return Event{}, false
+	}
+	ev := Event{Tag: tag}
+	switch t := e[0].(type) {
+	case msgpack.EventTime:
+		ev.Time = time.Time(t)
+	case int64:
+		ev.Time = time.Unix(t, 0)
+	case uint64:
+		ev.Time = time.Unix(int64(t), 0)
+	}
+	ev.Record, _ = e[1].(map[string]interface{})
+	return ev, true
+}
+
+// handshake - sends HELO, checks PING and replies PONG
+func (s *Server) handshake(c net.Conn, dec *msgpack.Decoder) bool {
+	nonce := "nonce"
+	var e msgpack.Encoder
+	e.EncodeArrayLen(2)
+	e.EncodeString("HELO")
+	e.Encode(map[string]interface{}{"nonce": []byte(nonce), "auth": []byte(""), "keepalive": true})
+	if _, err := c.Write(e.Bytes()); err != nil {
+		return false
+	}
+
+	v, err := dec.Decode()
+	if err != nil {
+		return false
+	}
+	ping, ok := v.([]interface{})
+	if !ok || len(ping) < 6 {
+		return false
+	}
+	clientHostname, _ := ping[1].(string)
+	salt, _ := ping[2].(string)
+	digest, _ := ping[3].(string)
+	authOK := digest == sum(salt, clientHostname, nonce, s.sharedKey)
+
+	reason := ""
+	if !authOK {
+		reason = "shared_key mismatch"
+	}
+	e.Reset()
+	e.EncodeArrayLen(5)
+	e.EncodeString("PONG")
+	e.EncodeBool(authOK)
+	e.EncodeString(reason)
+	e.EncodeString(Hostname)
+	e.EncodeString(sum(salt, Hostname, nonce, s.sharedKey))
+	if _, err := c.Write(e.Bytes()); err != nil {
+		return false
+	}
+	return authOK
+}
+
+func sum(parts ...string) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
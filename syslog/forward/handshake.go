@@ -0,0 +1,92 @@
+package forward
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"slogger/syslog/forward/msgpack"
+)
+
+// Digest - hex SHA-512 of concatenated parts, used by handshake for shared key and password digests
+func Digest(parts ...string) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// randomHex - returns n random bytes in hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// asString - returns msgpack str or bin value as string
+func asString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	}
+	return ""
+}
+
+// handshake - performs shared key authentication: reads HELO, sends PING, reads and verifies PONG.
+// It must be called with w.mu held
+func (w *Writer) handshake() error {
+	v, err := w.dec.Decode()
+	if err != nil {
+		return err
+	}
+	helo, ok := v.([]interface{})
+	if !ok || len(helo) < 2 || asString(helo[0]) != "HELO" {
+		return errors.New("forward: HELO expected")
+	}
+	opts, _ := helo[1].(map[string]interface{})
+	nonce := asString(opts["nonce"])
+	authSalt := asString(opts["auth"])
+
+	salt, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	passwordDigest := ""
+	if authSalt != "" {
+		passwordDigest = Digest(authSalt, w.cfg.Username, w.cfg.Password)
+	}
+
+	var e msgpack.Encoder
+	e.EncodeArrayLen(6)
+	e.EncodeString("PING")
+	e.EncodeString(w.cfg.SelfHostname)
+	e.EncodeString(salt)
+	e.EncodeString(Digest(salt, w.cfg.SelfHostname, nonce, w.cfg.SharedKey))
+	e.EncodeString(w.cfg.Username)
+	e.EncodeString(passwordDigest)
+	if _, err := w.conn.Write(e.Bytes()); err != nil {
+		return err
+	}
+
+	if v, err = w.dec.Decode(); err != nil {
+		return err
+	}
+	pong, ok := v.([]interface{})
+	if !ok || len(pong) < 5 || asString(pong[0]) != "PONG" {
+		return errors.New("forward: PONG expected")
+	}
+	if authOK, _ := pong[1].(bool); !authOK {
+		return fmt.Errorf("forward: authentication failed: %s", asString(pong[2]))
+	}
+	if asString(pong[4]) != Digest(salt, asString(pong[3]), nonce, w.cfg.SharedKey) {
+		return errors.New("forward: server shared key mismatch")
+	}
+	return nil
+}
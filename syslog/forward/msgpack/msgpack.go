@@ -0,0 +1,400 @@
+// Package msgpack - minimal MessagePack encoder and decoder used by Forward protocol
+package msgpack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// EventTime - fluentd EventTime (msgpack extension type 0), time with nanoseconds
+type EventTime time.Time
+
+// Ext - msgpack extension value
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// Encoder - minimal msgpack encoder
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes - returns encoded data
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Reset - clears encoded data
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+}
+
+func (e *Encoder) byte1(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *Encoder) uint16(b byte, n uint16) {
+	e.buf = append(e.buf, b, 0, 0)
+	binary.BigEndian.PutUint16(e.buf[len(e.buf)-2:], n)
+}
+
+func (e *Encoder) uint32(b byte, n uint32) {
+	e.buf = append(e.buf, b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(e.buf[len(e.buf)-4:], n)
+}
+
+func (e *Encoder) uint64(b byte, n uint64) {
+	e.buf = append(e.buf, b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(e.buf[len(e.buf)-8:], n)
+}
+
+// EncodeNil - writes nil
+func (e *Encoder) EncodeNil() {
+	e.byte1(0xc0)
+}
+
+// EncodeBool - writes bool
+func (e *Encoder) EncodeBool(b bool) {
+	if b {
+		e.byte1(0xc3)
+	} else {
+		e.byte1(0xc2)
+	}
+}
+
+// EncodeInt - writes signed integer in the shortest form
+func (e *Encoder) EncodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.EncodeUint(uint64(n))
+	case n >= -32:
+		e.byte1(byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.uint16(0xd1, uint16(n))
+	case n >= math.MinInt32:
+		e.uint32(0xd2, uint32(n))
+	default:
+		e.uint64(0xd3, uint64(n))
+	}
+}
+
+// EncodeUint - writes unsigned integer in the shortest form
+func (e *Encoder) EncodeUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.byte1(byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xcd, uint16(n))
+	case n <= math.MaxUint32:
+		e.uint32(0xce, uint32(n))
+	default:
+		e.uint64(0xcf, n)
+	}
+}
+
+// EncodeFloat - writes float64
+func (e *Encoder) EncodeFloat(f float64) {
+	e.uint64(0xcb, math.Float64bits(f))
+}
+
+// EncodeString - writes str
+func (e *Encoder) EncodeString(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		e.byte1(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xda, uint16(n))
+	default:
+		e.uint32(0xdb, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+// EncodeBytes - writes bin
+func (e *Encoder) EncodeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xc5, uint16(n))
+	default:
+		e.uint32(0xc6, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// EncodeArrayLen - writes array header, n values must follow
+func (e *Encoder) EncodeArrayLen(n int) {
+	switch {
+	case n <= 15:
+		e.byte1(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xdc, uint16(n))
+	default:
+		e.uint32(0xdd, uint32(n))
+	}
+}
+
+// EncodeMapLen - writes map header, n key-value pairs must follow
+func (e *Encoder) EncodeMapLen(n int) {
+	switch {
+	case n <= 15:
+		e.byte1(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xde, uint16(n))
+	default:
+		e.uint32(0xdf, uint32(n))
+	}
+}
+
+// EncodeEventTime - writes fluentd EventTime: fixext8 of type 0 with seconds and nanoseconds
+func (e *Encoder) EncodeEventTime(t time.Time) {
+	e.buf = append(e.buf, 0xd7, 0x00, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(e.buf[len(e.buf)-8:], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(e.buf[len(e.buf)-4:], uint32(t.Nanosecond()))
+}
+
+// Encode - writes value of supported type: nil, bool, integers, floats, string, []byte, time.Time
+// (as EventTime), []interface{}, map[string]interface{} (keys sorted); other types as fmt.Sprint string
+func (e *Encoder) Encode(v interface{}) {
+	switch t := v.(type) {
+	case nil:
+		e.EncodeNil()
+	case bool:
+		e.EncodeBool(t)
+	case int:
+		e.EncodeInt(int64(t))
+	case int8:
+		e.EncodeInt(int64(t))
+	case int16:
+		e.EncodeInt(int64(t))
+	case int32:
+		e.EncodeInt(int64(t))
+	case int64:
+		e.EncodeInt(t)
+	case uint:
+		e.EncodeUint(uint64(t))
+	case uint8:
+		e.EncodeUint(uint64(t))
+	case uint16:
+		e.EncodeUint(uint64(t))
+	case uint32:
+		e.EncodeUint(uint64(t))
+	case uint64:
+		e.EncodeUint(t)
+	case float32:
+		e.EncodeFloat(float64(t))
+	case float64:
+		e.EncodeFloat(t)
+	case string:
+		e.EncodeString(t)
+	case []byte:
+		e.EncodeBytes(t)
+	case time.Time:
+		e.EncodeEventTime(t)
+	case EventTime:
+		e.EncodeEventTime(time.Time(t))
+	case []interface{}:
+		e.EncodeArrayLen(len(t))
+		for _, v := range t {
+			e.Encode(v)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		e.EncodeMapLen(len(t))
+		for _, k := range keys {
+			e.EncodeString(k)
+			e.Encode(t[k])
+		}
+	default:
+		e.EncodeString(fmt.Sprint(v))
+	}
+}
+
+// Decoder - minimal msgpack decoder. Values are decoded as nil, bool, int64, uint64, float64, string,
+// []byte, EventTime, Ext, []interface{} and map[string]interface{} (non-string keys are formatted with fmt.Sprint)
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder - creates decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// errFormat - unsupported or malformed msgpack data
+var errFormat = errors.New("msgpack: invalid format")
+
+func (d *Decoder) read(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	return b, err
+}
+
+func (d *Decoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// Decode - reads next value
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		b, err := d.read(int(c & 0x1f))
+		return string(b), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.read(int(n))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		n, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.readUint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.readUint(1 << (c - 0xcc))
+	case 0xd0:
+		n, err := d.readUint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.readUint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.readUint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.readUint(8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n))
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, errFormat
+}
+
+func (d *Decoder) decodeExt(n int) (interface{}, error) {
+	t, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if t == 0 && n == 8 {
+		sec := binary.BigEndian.Uint32(b)
+		nsec := binary.BigEndian.Uint32(b[4:])
+		return EventTime(time.Unix(int64(sec), int64(nsec))), nil
+	}
+	return Ext{Type: int8(t), Data: b}, nil
+}
+
+func (d *Decoder) decodeArray(n int) (interface{}, error) {
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (d *Decoder) decodeMap(n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := k.(string); ok {
+			m[s] = v
+		} else {
+			m[fmt.Sprint(k)] = v
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,65 @@
+package msgpack
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncoder_RoundTrip(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+	long := strings.Repeat("x", 70000)
+	values := []interface{}{
+		nil, true, false,
+		int64(0), int64(127), int64(-1), int64(-32), int64(-33), int64(-200), int64(-40000), int64(-3000000000),
+		uint64(128), uint64(300), uint64(70000), uint64(5000000000),
+		1.5, "", "short", strings.Repeat("y", 40), strings.Repeat("z", 300), long,
+		[]byte{1, 2, 3},
+		[]interface{}{int64(1), "a", []interface{}{}},
+		map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": "d"}},
+		EventTime(ts),
+	}
+
+	var e Encoder
+	for _, v := range values {
+		e.Encode(v)
+	}
+	d := NewDecoder(bytes.NewReader(e.Bytes()))
+	for i, expect := range values {
+		v, err := d.Decode()
+		if err != nil {
+			t.Fatalf("expect no error of value %d, got: %v", i, err)
+		}
+		if et, ok := expect.(EventTime); ok {
+			if got, ok := v.(EventTime); !ok || !time.Time(got).Equal(time.Time(et)) {
+				t.Errorf("expect %v, got: %v", et, v)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("expect %#v, got: %#v", expect, v)
+		}
+	}
+}
+
+func TestEncoder_Shortest(t *testing.T) {
+	cases := []struct {
+		v      interface{}
+		expect []byte
+	}{
+		{5, []byte{0x05}},
+		{-5, []byte{0xfb}},
+		{200, []byte{0xcc, 0xc8}},
+		{"ab", []byte{0xa2, 'a', 'b'}},
+		{time.Unix(1, 2), []byte{0xd7, 0x00, 0, 0, 0, 1, 0, 0, 0, 2}},
+	}
+	for _, c := range cases {
+		var e Encoder
+		e.Encode(c.v)
+		if !bytes.Equal(e.Bytes(), c.expect) {
+			t.Errorf("expect % x of %v, got: % x", c.expect, c.v, e.Bytes())
+		}
+	}
+}
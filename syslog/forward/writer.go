@@ -0,0 +1,354 @@
+// Package forward implements Fluentd Forward protocol sink (Fluentd, Fluent Bit) over tcp or unix socket
+package forward
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"slogger/syslog/conncheck"
+	"slogger/syslog/format"
+	"slogger/syslog/forward/msgpack"
+)
+
+const (
+	// DefaultTimeout - dial, handshake and write timeout
+	DefaultTimeout = 5 * time.Second
+	// DefaultAckTimeout - time to wait for ack of chunk
+	DefaultAckTimeout = 10 * time.Second
+)
+
+// record keys of event besides record fields
+const (
+	KeyMessage  = "message"
+	KeySeverity = "severity"
+	KeyFacility = "facility"
+	KeyHost     = "host"
+	KeyIdent    = "ident"
+	KeyPID      = "pid"
+	KeyCaller   = "caller"
+	KeyTraceID  = "trace_id"
+	KeySpanID   = "span_id"
+)
+
+const (
+	severityMask = 0x07
+	facilityMask = 0xf8
+)
+
+var facilityNames = map[syslog.Priority]string{
+	syslog.LOG_KERN: "kern", syslog.LOG_USER: "user", syslog.LOG_MAIL: "mail", syslog.LOG_DAEMON: "daemon",
+	syslog.LOG_AUTH: "auth", syslog.LOG_SYSLOG: "syslog", syslog.LOG_LPR: "lpr", syslog.LOG_NEWS: "news",
+	syslog.LOG_UUCP: "uucp", syslog.LOG_CRON: "cron", syslog.LOG_AUTHPRIV: "authpriv", syslog.LOG_FTP: "ftp",
+	syslog.LOG_LOCAL0: "local0", syslog.LOG_LOCAL1: "local1", syslog.LOG_LOCAL2: "local2", syslog.LOG_LOCAL3: "local3",
+	syslog.LOG_LOCAL4: "local4", syslog.LOG_LOCAL5: "local5", syslog.LOG_LOCAL6: "local6", syslog.LOG_LOCAL7: "local7",
+}
+
+// Config - Forward sink settings
+type Config struct {
+	// Tag - fluentd tag of events, syslog tag if empty
+	Tag string
+	// RequireAck - send chunk ID with every batch and wait for ack (at-least-once delivery).
+	// Batch without ack is sent again over new connection
+	RequireAck bool
+	// AckTimeout - time to wait for ack, DefaultAckTimeout if zero
+	AckTimeout time.Duration
+	// SharedKey - enables shared key handshake (fluentd <security>, Fluent Bit Shared_Key)
+	SharedKey string
+	// Username, Password - user authentication of handshake, used if server requires it
+	Username string
+	Password string
+	// SelfHostname - hostname sent in handshake, os.Hostname if empty
+	SelfHostname string
+	// Timeout - dial, handshake and write timeout, DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+// Writer - Forward protocol writer. Batch of records is sent as one PackedForward message.
+// It implements syslog.SyslogWriter, syslog.RecordWriter and syslog.BatchWriter, so can be used as sender sink
+type Writer struct {
+	priority syslog.Priority
+	tag      string
+	hostname string
+	network  string
+	raddr    string
+	cfg      Config
+
+	mu   sync.Mutex
+	conn net.Conn
+	dec  *msgpack.Decoder
+}
+
+// Dial - connects to Forward input. network is "tcp" or "unix"
+func Dial(network, raddr string, priority syslog.Priority, tag string, cfg Config) (*Writer, error) {
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("forward: unsupported network %q", network)
+	}
+	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
+		return nil, errors.New("forward: invalid priority")
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	hostname, _ := os.Hostname()
+	if cfg.Tag == "" {
+		cfg.Tag = tag
+	}
+	if cfg.SelfHostname == "" {
+		cfg.SelfHostname = hostname
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.AckTimeout == 0 {
+		cfg.AckTimeout = DefaultAckTimeout
+	}
+
+	w := &Writer{
+		priority: priority,
+		tag:      tag,
+		hostname: hostname,
+		network:  network,
+		raddr:    raddr,
+		cfg:      cfg,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect makes a connection to the Forward input and performs handshake if shared key is set.
+// It must be called with w.mu held.
+func (w *Writer) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	conn, err := net.DialTimeout(w.network, w.raddr, w.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	w.dec = msgpack.NewDecoder(conn)
+	if w.hostname == "" {
+		w.hostname = conn.LocalAddr().String()
+	}
+
+	if w.cfg.SharedKey != "" {
+		conn.SetDeadline(time.Now().Add(w.cfg.Timeout))
+		err = w.handshake()
+		conn.SetDeadline(time.Time{})
+		if err != nil {
+			conn.Close()
+			w.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckConn - checks that connection kept between batches is not broken (e.g. closed by server)
+func (w *Writer) CheckConn() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return errors.New("forward: not connected")
+	}
+	return conncheck.ReadProbe(w.conn)
+}
+
+// Close - closes connection
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.WriteRecord(&format.Record{Priority: w.priority, Message: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - message field of Forward events keeps line breaks of multi-line messages, so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - sends one record
+func (w *Writer) WriteRecord(r *format.Record) error {
+	return w.WriteRecords([]*format.Record{r})
+}
+
+// WriteRecords - sends records as one PackedForward message, waits for ack if RequireAck is set
+func (w *Writer) WriteRecords(recs []*format.Record) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	chunk := ""
+	if w.cfg.RequireAck {
+		var err error
+		if chunk, err = randomHex(16); err != nil {
+			return err
+		}
+	}
+	msg := w.encode(recs, chunk, time.Now())
+	return w.writeAndRetry(msg, chunk)
+}
+
+func (w *Writer) writeAndRetry(msg []byte, chunk string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if err := w.write(msg, chunk); err == nil {
+			return nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	return w.write(msg, chunk)
+}
+
+// write sends message and reads ack of chunk.
+// It must be called with w.mu held.
+func (w *Writer) write(msg []byte, chunk string) error {
+	w.conn.SetWriteDeadline(time.Now().Add(w.cfg.Timeout))
+	if _, err := w.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	w.conn.SetReadDeadline(time.Now().Add(w.cfg.AckTimeout))
+	defer w.conn.SetReadDeadline(time.Time{})
+	v, err := w.dec.Decode()
+	if err != nil {
+		return err
+	}
+	rsp, _ := v.(map[string]interface{})
+	if ack := asString(rsp["ack"]); ack != chunk {
+		return fmt.Errorf("forward: unexpected ack %q of chunk %q", ack, chunk)
+	}
+	return nil
+}
+
+// encode - builds PackedForward message [tag, entries, option]
+func (w *Writer) encode(recs []*format.Record, chunk string, now time.Time) []byte {
+	var entries msgpack.Encoder
+	for _, r := range recs {
+		ts := r.Timestamp
+		if ts.IsZero() {
+			ts = now
+		}
+		entries.EncodeArrayLen(2)
+		entries.EncodeEventTime(ts)
+		entries.Encode(w.record(r))
+	}
+
+	var e msgpack.Encoder
+	e.EncodeArrayLen(3)
+	e.EncodeString(w.cfg.Tag)
+	e.EncodeBytes(entries.Bytes())
+	if chunk != "" {
+		e.EncodeMapLen(2)
+		e.EncodeString("chunk")
+		e.EncodeString(chunk)
+	} else {
+		e.EncodeMapLen(1)
+	}
+	e.EncodeString("size")
+	e.EncodeInt(int64(len(recs)))
+	return e.Bytes()
+}
+
+// record - returns event record: fields of r, message, severity, facility and identity keys
+func (w *Writer) record(r *format.Record) map[string]interface{} {
+	p := (w.priority & facilityMask) | (r.Priority & severityMask)
+	m := make(map[string]interface{}, len(r.Fields)+9)
+	for k, v := range r.Fields {
+		m[k] = v
+	}
+	m[KeyMessage] = r.Message
+	m[KeySeverity] = format.SeverityName(p)
+	m[KeyFacility] = facilityNames[p&facilityMask]
+
+	m[KeyHost] = w.hostname
+	if r.Hostname != "" {
+		m[KeyHost] = r.Hostname
+	}
+	m[KeyIdent] = w.tag
+	if r.Tag != "" {
+		m[KeyIdent] = r.Tag
+	}
+	switch {
+	case r.ProcID != "":
+		m[KeyPID] = r.ProcID
+	case r.PID > 0:
+		m[KeyPID] = r.PID
+	default:
+		m[KeyPID] = os.Getpid()
+	}
+	if r.Caller != "" {
+		m[KeyCaller] = r.Caller
+	}
+	if r.TraceID != "" {
+		m[KeyTraceID] = r.TraceID
+	}
+	if r.SpanID != "" {
+		m[KeySpanID] = r.SpanID
+	}
+	return m
+}
@@ -0,0 +1,150 @@
+package forward
+
+import (
+	"io/ioutil"
+	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/forward/forwardtest"
+)
+
+func TestWriter_WriteRecords(t *testing.T) {
+	srv, err := forwardtest.NewServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	w, err := Dial("tcp", srv.Addr, syslog.LOG_WARNING|syslog.LOG_LOCAL3, "app", Config{Tag: "k8s.app", RequireAck: true})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	ts := time.Unix(1700000000, 5000)
+	err = w.WriteRecords([]*format.Record{
+		{Priority: syslog.LOG_ERR, Timestamp: ts, Message: "first", Caller: "main.go:10",
+			Fields: format.Fields{"user": "bob", "n": 3}, TraceID: "abc"},
+		{Priority: syslog.LOG_INFO, Message: "second", PID: 42},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	events := srv.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if len(srv.Chunks()) != 1 {
+		t.Errorf("expect 1 chunk, got: %v", srv.Chunks())
+	}
+	e := events[0]
+	if e.Tag != "k8s.app" {
+		t.Errorf("expect tag k8s.app, got: %s", e.Tag)
+	}
+	if !e.Time.Equal(ts) {
+		t.Errorf("expect time %v, got: %v", ts, e.Time)
+	}
+	expect := map[string]interface{}{
+		KeyMessage: "first", KeySeverity: "err", KeyFacility: "local3", KeyIdent: "app",
+		KeyCaller: "main.go:10", KeyTraceID: "abc", "user": "bob", "n": int64(3),
+	}
+	for k, v := range expect {
+		if e.Record[k] != v {
+			t.Errorf("expect %s=%v, got: %v", k, v, e.Record[k])
+		}
+	}
+	if events[1].Record[KeyPID] != int64(42) {
+		t.Errorf("expect pid 42, got: %v", events[1].Record[KeyPID])
+	}
+}
+
+func TestWriter_AckRetry(t *testing.T) {
+	srv, err := forwardtest.NewServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	w, err := Dial("tcp", srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app",
+		Config{RequireAck: true, AckTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	srv.DropAcks(1)
+	if err := w.Err("lost ack"); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	chunks := srv.Chunks()
+	if len(chunks) != 2 || chunks[0] != chunks[1] {
+		t.Errorf("expect chunk sent twice with the same ID, got: %v", chunks)
+	}
+	if n := len(srv.Events()); n != 2 {
+		t.Errorf("expect 2 events (at-least-once), got: %d", n)
+	}
+
+	srv.DropAcks(2)
+	if err := w.Err("lost acks"); err == nil {
+		t.Errorf("expect error of not acknowledged chunk, got: %v", err)
+	}
+}
+
+func TestWriter_SharedKey(t *testing.T) {
+	srv, err := forwardtest.NewServer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	w, err := Dial("tcp", srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{SharedKey: "secret", RequireAck: true})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if err := w.Info("authenticated"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	w.Close()
+	if n := len(srv.Events()); n != 1 {
+		t.Errorf("expect 1 event, got: %d", n)
+	}
+
+	if _, err := Dial("tcp", srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{SharedKey: "wrong"}); err == nil {
+		t.Errorf("expect handshake error, got: %v", err)
+	}
+}
+
+func TestWriter_Unix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forward")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ln, err := net.Listen("unix", filepath.Join(dir, "forward.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := forwardtest.Serve(ln, "")
+	defer srv.Close()
+
+	w, err := Dial("unix", srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{RequireAck: true})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Warning("over unix"); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if events := srv.Events(); len(events) != 1 || events[0].Record[KeyMessage] != "over unix" {
+		t.Errorf("expect event over unix, got: %v", events)
+	}
+}
@@ -0,0 +1,102 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"fmt"
+)
+
+// Compression - compression of GELF UDP messages
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZlib
+)
+
+const (
+	// ChunkSizeWAN - default UDP chunk size, safe for WAN
+	ChunkSizeWAN = 1420
+	// ChunkSizeLAN - UDP chunk size for LAN with jumbo frames
+	ChunkSizeLAN = 8154
+
+	chunkHeaderLen = 12
+	maxChunks      = 128
+)
+
+// Maximum message sizes. Part of payload limit is left for JSON envelope, fields and escaping
+const (
+	payloadReserve = 16 << 10
+
+	// MaxMessageSizeUDP - message fitting maxChunks chunks of ChunkSizeWAN
+	MaxMessageSizeUDP = maxChunks*(ChunkSizeWAN-chunkHeaderLen) - payloadReserve
+	// MaxMessageSizeTCP - Graylog GELF TCP input default maximum message size (2 MiB)
+	MaxMessageSizeTCP = 2<<20 - payloadReserve
+)
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// compress - compresses GELF payload
+func compress(c Compression, p []byte) ([]byte, error) {
+	b := new(bytes.Buffer)
+	switch c {
+	case CompressionGzip:
+		w := gzip.NewWriter(b)
+		if _, err := w.Write(p); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZlib:
+		w := zlib.NewWriter(b)
+		if _, err := w.Write(p); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return p, nil
+	}
+	return b.Bytes(), nil
+}
+
+// chunks - splits payload to GELF UDP chunks. Returns payload as is if it fits chunkSize
+func chunks(p []byte, chunkSize int) ([][]byte, error) {
+	if len(p) <= chunkSize {
+		return [][]byte{p}, nil
+	}
+
+	dataSize := chunkSize - chunkHeaderLen
+	if dataSize <= 0 {
+		return nil, fmt.Errorf("gelf: chunk size %d is too small", chunkSize)
+	}
+	cnt := (len(p) + dataSize - 1) / dataSize
+	if cnt > maxChunks {
+		return nil, fmt.Errorf("gelf: message of %d bytes needs %d chunks, maximum is %d", len(p), cnt, maxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("gelf: cannot generate message id: %v", err)
+	}
+
+	res := make([][]byte, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		end := (i + 1) * dataSize
+		if end > len(p) {
+			end = len(p)
+		}
+		c := make([]byte, 0, chunkHeaderLen+end-i*dataSize)
+		c = append(c, chunkMagic...)
+		c = append(c, id...)
+		c = append(c, byte(i), byte(cnt))
+		c = append(c, p[i*dataSize:end]...)
+		res = append(res, c)
+	}
+	return res, nil
+}
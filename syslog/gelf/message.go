@@ -0,0 +1,97 @@
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"math"
+	"regexp"
+	"strings"
+
+	"slogger/syslog/format"
+)
+
+const gelfVersion = "1.1"
+
+// shortMessageLen - messages longer than this (or multi-line ones) are also sent as full_message
+const shortMessageLen = 250
+
+// emptyShortMessage - short_message of empty messages
+const emptyShortMessage = "-"
+
+var invalidFieldChars = regexp.MustCompile(`[^\w\.\-]`)
+
+// Level - GELF level of syslog priority. GELF uses syslog severities as levels
+func Level(p syslog.Priority) int {
+	return int(p & 0x07)
+}
+
+// encode - builds GELF 1.1 JSON payload of record
+func encode(r *format.Record) ([]byte, error) {
+	msg := strings.TrimSuffix(r.Message, "\n")
+	short := msg
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = short[:i]
+	}
+	if len(short) > shortMessageLen {
+		short = format.TruncateUTF8(short, shortMessageLen)
+	}
+	if strings.TrimSpace(short) == "" {
+		// Graylog rejects messages with empty short_message
+		short = emptyShortMessage
+	}
+
+	obj := make(map[string]interface{}, len(r.Fields)+8)
+	for k, v := range r.Fields {
+		k = "_" + invalidFieldChars.ReplaceAllString(k, "_")
+		if k == "_id" || k == "_" {
+			// "_id" is reserved by GELF
+			continue
+		}
+		obj[k] = fieldValue(v)
+	}
+	obj["version"] = gelfVersion
+	obj["host"] = r.Hostname
+	obj["short_message"] = short
+	if short != msg && msg != "" {
+		obj["full_message"] = msg
+	}
+	obj["timestamp"] = float64(r.Timestamp.UnixNano()/1e6) / 1e3
+	obj["level"] = Level(r.Priority)
+	if r.Tag != "" {
+		obj["_tag"] = r.Tag
+	}
+	if r.Caller != "" {
+		obj["_caller"] = r.Caller
+	}
+
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// fieldValue - GELF additional fields may be only strings or numbers. NaN and infinities are not
+// valid JSON numbers, so they are sent as strings
+func fieldValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case float32:
+		return fieldValue(float64(vv))
+	case float64:
+		if math.IsNaN(vv) || math.IsInf(vv, 0) {
+			return fmt.Sprint(vv)
+		}
+		return v
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case error:
+		return vv.Error()
+	case fmt.Stringer:
+		return vv.String()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,231 @@
+package gelf
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"slogger/syslog/conncheck"
+	"slogger/syslog/format"
+)
+
+// Writer - GELF writer over udp (chunked, optionally compressed) or tcp (null byte delimited).
+// It implements syslog.SyslogWriter and syslog.RecordWriter, so can be used as sender sink
+type Writer struct {
+	priority syslog.Priority
+	tag      string
+	hostname string
+	network  string
+	raddr    string
+
+	compression Compression
+	chunkSize   int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Dial - connects to GELF input. network is "udp" or "tcp"
+func Dial(network, raddr string, priority syslog.Priority, tag string) (*Writer, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("gelf: unsupported network %q", network)
+	}
+	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
+		return nil, errors.New("gelf: invalid priority")
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	hostname, _ := os.Hostname()
+
+	w := &Writer{
+		priority:  priority,
+		tag:       tag,
+		hostname:  hostname,
+		network:   network,
+		raddr:     raddr,
+		chunkSize: ChunkSizeWAN,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetCompression - set compression of udp messages. GELF tcp input does not support compression
+func (w *Writer) SetCompression(c Compression) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.compression = c
+}
+
+// SetChunkSize - set maximum udp datagram size, ChunkSizeWAN by default
+func (w *Writer) SetChunkSize(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.chunkSize = n
+}
+
+// connect makes a connection to the GELF input.
+// It must be called with w.mu held.
+func (w *Writer) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	w.conn, err = net.Dial(w.network, w.raddr)
+	if err != nil {
+		return err
+	}
+	if w.hostname == "" {
+		w.hostname = w.conn.LocalAddr().String()
+	}
+	return nil
+}
+
+// CheckConn - checks that tcp connection kept between batches is not broken (e.g. closed by server).
+// Udp connection is not checked
+func (w *Writer) CheckConn() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return errors.New("gelf: not connected")
+	}
+	if w.network != "tcp" {
+		return nil
+	}
+	return conncheck.ReadProbe(w.conn)
+}
+
+// Close - closes connection
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.writeAndRetry(&format.Record{Priority: w.priority, Message: string(b)})
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - full_message of GELF keeps line breaks of multi-line messages, so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - sends record as GELF message, fields are sent as additional ("_") fields
+func (w *Writer) WriteRecord(r *format.Record) error {
+	_, err := w.writeAndRetry(r)
+	return err
+}
+
+func (w *Writer) writeAndRetry(r *format.Record) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rec := *r
+	if rec.Tag == "" {
+		rec.Tag = w.tag
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+
+	if rec.Hostname == "" {
+		rec.Hostname = w.hostname
+	}
+	// record which cannot be encoded or chunked is dropped, reconnecting does not help
+	packets, err := w.packets(&rec)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", format.ErrDropped, err)
+	}
+
+	if w.conn != nil {
+		if err := w.write(packets); err == nil {
+			return len(r.Message), nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if err := w.write(packets); err != nil {
+		return 0, err
+	}
+	return len(r.Message), nil
+}
+
+// packets - returns tcp frame or udp chunks of record
+func (w *Writer) packets(r *format.Record) ([][]byte, error) {
+	p, err := encode(r)
+	if err != nil {
+		return nil, err
+	}
+	if w.network == "tcp" {
+		return [][]byte{append(p, 0)}, nil
+	}
+	if p, err = compress(w.compression, p); err != nil {
+		return nil, err
+	}
+	return chunks(p, w.chunkSize)
+}
+
+// write sends packets over connection.
+// It must be called with w.mu held.
+func (w *Writer) write(packets [][]byte) error {
+	for _, p := range packets {
+		if _, err := w.conn.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log/syslog"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// readUDPMessage - reads datagrams until GELF message is complete and returns decompressed payload
+func readUDPMessage(t *testing.T, pc net.PacketConn) []byte {
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var (
+		parts   [][]byte
+		receive int
+	)
+	b := make([]byte, 65536)
+	for {
+		n, _, err := pc.ReadFrom(b)
+		if err != nil {
+			t.Fatalf("cannot read datagram: %v", err)
+		}
+		d := append([]byte(nil), b[:n]...)
+		if !bytes.HasPrefix(d, chunkMagic) {
+			return decompress(t, d)
+		}
+		if parts == nil {
+			parts = make([][]byte, d[11])
+		}
+		if parts[d[10]] == nil {
+			receive++
+		}
+		parts[d[10]] = d[chunkHeaderLen:]
+		if receive == len(parts) {
+			return decompress(t, bytes.Join(parts, nil))
+		}
+	}
+}
+
+func decompress(t *testing.T, p []byte) []byte {
+	var (
+		res []byte
+		err error
+	)
+	r := bytes.NewReader(p)
+	switch {
+	case bytes.HasPrefix(p, []byte{0x1f, 0x8b}):
+		zr, zErr := gzip.NewReader(r)
+		if zErr != nil {
+			t.Fatalf("cannot read gzip: %v", zErr)
+		}
+		res, err = ioutil.ReadAll(zr)
+	case p[0] == 0x78:
+		zr, zErr := zlib.NewReader(r)
+		if zErr != nil {
+			t.Fatalf("cannot read zlib: %v", zErr)
+		}
+		res, err = ioutil.ReadAll(zr)
+	default:
+		res = p
+	}
+	if err != nil {
+		t.Fatalf("cannot decompress: %v", err)
+	}
+	return res
+}
+
+func decodeMessage(t *testing.T, p []byte) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(p, &obj); err != nil {
+		t.Fatalf("expect valid JSON, got %q: %v", p, err)
+	}
+	return obj
+}
+
+func TestWriter_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer pc.Close()
+
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZlib} {
+		w, err := Dial("udp", pc.LocalAddr().String(), syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+		if err != nil {
+			t.Fatalf("cannot dial: %v", err)
+		}
+		w.SetCompression(c)
+
+		if err := w.WriteRecord(&format.Record{
+			Priority: syslog.LOG_ERR,
+			Message:  "Test message",
+			Fields:   format.Fields{"request id": "abc", "id": 1},
+		}); err != nil {
+			t.Errorf("expect no error, got: %v", err)
+		}
+
+		obj := decodeMessage(t, readUDPMessage(t, pc))
+		if obj["version"] != "1.1" || obj["short_message"] != "Test message" || obj["level"] != float64(3) ||
+			obj["_tag"] != "tag" || obj["_request_id"] != "abc" {
+			t.Errorf("compression %d: unexpected message: %v", c, obj)
+		}
+		if _, ok := obj["_id"]; ok {
+			t.Errorf("compression %d: expect no _id field, got: %v", c, obj)
+		}
+		w.Close()
+	}
+}
+
+func TestWriter_UDPChunked(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer pc.Close()
+
+	w, err := Dial("udp", pc.LocalAddr().String(), syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+	w.SetChunkSize(512)
+
+	m := strings.Repeat("0123456789", 300) + "\nsecond line"
+	if err := w.Err(m); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	obj := decodeMessage(t, readUDPMessage(t, pc))
+	if obj["full_message"] != m {
+		t.Errorf("expect full_message of %d bytes, got: %v", len(m), obj["full_message"])
+	}
+	if s, _ := obj["short_message"].(string); len(s) != shortMessageLen {
+		t.Errorf("expect short_message of %d bytes, got %d", shortMessageLen, len(s))
+	}
+
+	conn := w.conn
+	if err := w.Err(strings.Repeat("0", 512*maxChunks)); !errors.Is(err, format.ErrDropped) {
+		t.Errorf("expect record dropped for message exceeding %d chunks, got: %v", maxChunks, err)
+	}
+	if w.conn != conn {
+		t.Errorf("expect connection kept after dropped record")
+	}
+}
+
+func TestWriter_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	msgs := make(chan []byte, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			m, err := r.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			msgs <- bytes.TrimSuffix(m, []byte{0})
+		}
+	}()
+
+	w, err := Dial("tcp", ln.Addr().String(), syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+
+	w.Warning("first")
+	w.Info("second")
+	for _, expect := range []string{"first", "second"} {
+		select {
+		case m := <-msgs:
+			if obj := decodeMessage(t, m); obj["short_message"] != expect {
+				t.Errorf("expect %q, got %v", expect, obj["short_message"])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %q not received", expect)
+		}
+	}
+}
+
+func Test_encodeShortMessage(t *testing.T) {
+	// byte at shortMessageLen is in the middle of two-byte character
+	m := "a" + strings.Repeat("я", shortMessageLen)
+	p, err := encode(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	obj := decodeMessage(t, p)
+	s, _ := obj["short_message"].(string)
+	if s != m[:shortMessageLen-1] {
+		t.Errorf("expect short_message cut on UTF-8 boundary, got: %q", s)
+	}
+	if obj["full_message"] != m {
+		t.Errorf("expect full_message unchanged")
+	}
+}
+
+func Test_encodeValues(t *testing.T) {
+	p, err := encode(&format.Record{Priority: syslog.LOG_ERR, Message: "",
+		Fields: format.Fields{"nan": math.NaN(), "inf": float32(math.Inf(1)), "n": 1.5}})
+	if err != nil {
+		t.Fatalf("expect non-finite floats encoded, got: %v", err)
+	}
+	obj := decodeMessage(t, p)
+	if obj["_nan"] != "NaN" || obj["_inf"] != "+Inf" || obj["_n"] != 1.5 {
+		t.Errorf("unexpected fields: %v", obj)
+	}
+	if _, ok := obj["full_message"]; obj["short_message"] != emptyShortMessage || ok {
+		t.Errorf("expect placeholder short_message of empty message, got: %v", obj)
+	}
+}
@@ -0,0 +1,150 @@
+package httppush
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retry defaults
+const (
+	DefaultMaxRetries = 5
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+	DefaultTimeout    = 10 * time.Second
+)
+
+// DefaultClient - shared client, so connections are reused between writers
+var DefaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Retry - retry settings of push requests
+type Retry struct {
+	// MaxRetries - retries of retryable responses and network errors, DefaultMaxRetries if 0, -1 - no retries
+	MaxRetries int
+	// MinBackoff, MaxBackoff - exponential backoff between retries, DefaultMinBackoff and DefaultMaxBackoff if 0.
+	// Retry-After of response is used instead if set
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retryable - returns true for response status to be retried, 429 and 5xx if nil
+	Retryable func(status int) bool
+}
+
+// Request - push request
+type Request struct {
+	Client *http.Client
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Gzip - returns gzipped b
+func Gzip(b []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// errRetry - error which can be retried, with Retry-After delay if set
+type errRetry struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *errRetry) Error() string {
+	return e.err.Error()
+}
+
+// Post - posts request, retries network errors and retryable responses with exponential backoff.
+// Waiting between retries is interrupted when ctx is done; request itself is limited by client
+// timeout, so the first attempt is made even if ctx is already done (e.g. last batch on close)
+func Post(ctx context.Context, req Request, retry Retry) error {
+	if retry.MaxRetries == 0 {
+		retry.MaxRetries = DefaultMaxRetries
+	}
+	if retry.MinBackoff <= 0 {
+		retry.MinBackoff = DefaultMinBackoff
+	}
+	if retry.MaxBackoff <= 0 {
+		retry.MaxBackoff = DefaultMaxBackoff
+	}
+	if retry.Retryable == nil {
+		retry.Retryable = func(status int) bool {
+			return status == http.StatusTooManyRequests || status/100 == 5
+		}
+	}
+	if req.Client == nil {
+		req.Client = DefaultClient
+	}
+
+	backoff := retry.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := post(req, retry.Retryable)
+		if err == nil {
+			return nil
+		}
+		e, ok := err.(*errRetry)
+		if !ok || retry.MaxRetries < 0 || attempt >= retry.MaxRetries {
+			return err
+		}
+		delay := backoff
+		if e.delay > 0 {
+			delay = e.delay
+		}
+		if delay > retry.MaxBackoff {
+			delay = retry.MaxBackoff
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+		if backoff *= 2; backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+}
+
+func post(r Request, retryable func(int) bool) error {
+	req, err := http.NewRequest(http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range r.Header {
+		req.Header[k] = vs
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return &errRetry{err: err}
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("push to %s failed: %s: %s", r.URL, resp.Status, strings.TrimSpace(string(msg)))
+	if retryable(resp.StatusCode) {
+		e := &errRetry{err: err}
+		if s, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+			e.delay = time.Duration(s) * time.Second
+		}
+		return e
+	}
+	return err
+}
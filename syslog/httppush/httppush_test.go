@@ -0,0 +1,147 @@
+package httppush
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestServer - responds with statuses in turn and then 204, records request times
+func newTestServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, func() []time.Time) {
+	var (
+		mu    sync.Mutex
+		calls []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := len(calls)
+		calls = append(calls, time.Now())
+		mu.Unlock()
+
+		if n < len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return srv, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]time.Time(nil), calls...)
+	}
+}
+
+func TestPost_Retry(t *testing.T) {
+	srv, calls := newTestServer(t, nil, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	err := Post(context.Background(), Request{URL: srv.URL, Body: []byte("body")},
+		Retry{MinBackoff: 20 * time.Millisecond, MaxBackoff: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	c := calls()
+	if len(c) != 4 {
+		t.Fatalf("expect 4 requests, got: %d", len(c))
+	}
+	// backoff 20ms, 30ms (doubled 40ms is cut to MaxBackoff), 30ms
+	for i, min := range []time.Duration{20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond} {
+		if d := c[i+1].Sub(c[i]); d < min {
+			t.Errorf("retry %d: expect backoff at least %s, got: %s", i+1, min, d)
+		}
+	}
+}
+
+func TestPost_RetryAfter(t *testing.T) {
+	srv, calls := newTestServer(t, http.Header{"Retry-After": {"1"}}, http.StatusTooManyRequests)
+	defer srv.Close()
+
+	// Retry-After is limited by MaxBackoff
+	err := Post(context.Background(), Request{URL: srv.URL},
+		Retry{MinBackoff: time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	c := calls()
+	if len(c) != 2 {
+		t.Fatalf("expect 2 requests, got: %d", len(c))
+	}
+	if d := c[1].Sub(c[0]); d < 50*time.Millisecond || d > 500*time.Millisecond {
+		t.Errorf("expect delay of MaxBackoff, got: %s", d)
+	}
+}
+
+func TestPost_NoRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		retry  Retry
+		expect int
+	}{
+		{"not retryable", http.StatusBadRequest, Retry{MinBackoff: time.Millisecond}, 1},
+		{"retries disabled", http.StatusServiceUnavailable, Retry{MaxRetries: -1}, 1},
+		{"max retries", http.StatusServiceUnavailable, Retry{MaxRetries: 2, MinBackoff: time.Millisecond}, 3},
+		{"custom retryable", http.StatusServiceUnavailable, Retry{
+			MinBackoff: time.Millisecond,
+			Retryable:  func(status int) bool { return false },
+		}, 1},
+	} {
+		srv, calls := newTestServer(t, nil, tc.status, tc.status, tc.status, tc.status, tc.status, tc.status)
+		if err := Post(context.Background(), Request{URL: srv.URL}, tc.retry); err == nil {
+			t.Errorf("%s: expect error, got no error", tc.name)
+		}
+		if n := len(calls()); n != tc.expect {
+			t.Errorf("%s: expect %d requests, got: %d", tc.name, tc.expect, n)
+		}
+		srv.Close()
+	}
+}
+
+func TestPost_Context(t *testing.T) {
+	srv, calls := newTestServer(t, nil, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := Post(ctx, Request{URL: srv.URL}, Retry{MinBackoff: time.Hour, MaxBackoff: time.Hour})
+	if err == nil {
+		t.Errorf("expect error, got no error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expect backoff interrupted by context, got: %s", d)
+	}
+	if n := len(calls()); n != 1 {
+		t.Errorf("expect 1 request, got: %d", n)
+	}
+
+	// first attempt is made with done context
+	if err := Post(ctx, Request{URL: srv.URL}, Retry{}); err != nil {
+		t.Errorf("expect request sent with done context, got: %v", err)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	b, err := Gzip([]byte("body"))
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("cannot read gzip: %v", err)
+	}
+	if p, _ := ioutil.ReadAll(zr); string(p) != "body" {
+		t.Errorf("expect %q, got %q", "body", p)
+	}
+}
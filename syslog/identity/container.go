@@ -0,0 +1,93 @@
+package identity
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+
+	"slogger/syslog/format"
+)
+
+// Default paths of container ID sources
+const (
+	DefaultCgroupPath    = "/proc/self/cgroup"
+	DefaultMountinfoPath = "/proc/self/mountinfo"
+)
+
+// FieldContainerID - field with full container ID
+const FieldContainerID = "container_id"
+
+// containerIDShortLen - length of container ID used as hostname, like docker does
+const containerIDShortLen = 12
+
+var (
+	// cgroup v1 (docker, containerd, cri-o): "12:pids:/docker/<id>", "...:/kubepods/.../cri-containerd-<id>.scope"
+	cgroupContainerID = regexp.MustCompile(`[/-]([0-9a-f]{64})(?:\.scope)?\s*$`)
+	// cgroup v2 has no container ID in /proc/self/cgroup, but it is in overlay or
+	// "/containers/<id>/hostname" mount sources
+	mountinfoContainerID = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+)
+
+// Container - ID of container the process runs in. Sets hostname to short container ID
+// and FieldContainerID field to full ID. Returns empty identity outside containers
+type Container struct {
+	// CgroupPath - DefaultCgroupPath if empty
+	CgroupPath string
+	// MountinfoPath - DefaultMountinfoPath if empty
+	MountinfoPath string
+}
+
+// Identity - implements Provider
+func (c Container) Identity() (Identity, error) {
+	cgroupPath, mountinfoPath := c.CgroupPath, c.MountinfoPath
+	if cgroupPath == "" {
+		cgroupPath = DefaultCgroupPath
+	}
+	if mountinfoPath == "" {
+		mountinfoPath = DefaultMountinfoPath
+	}
+
+	id, err := findInFile(cgroupPath, cgroupContainerID)
+	if err != nil {
+		return Identity{}, err
+	}
+	if id == "" {
+		if id, err = findInFile(mountinfoPath, mountinfoContainerID); err != nil {
+			return Identity{}, err
+		}
+	}
+	if id == "" {
+		return Identity{}, nil
+	}
+
+	return Identity{
+		Hostname: id[:containerIDShortLen],
+		Fields:   format.Fields{FieldContainerID: id},
+	}, nil
+}
+
+// findInFile - returns first submatch of re in lines of file, empty string if
+// there is no match or no file
+func findInFile(path string, re *regexp.Regexp) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return findInReader(f, re)
+}
+
+func findInReader(r io.Reader, re *regexp.Regexp) (string, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if m := re.FindStringSubmatch(s.Text()); m != nil {
+			return m[1], nil
+		}
+	}
+	return "", s.Err()
+}
@@ -0,0 +1,45 @@
+package identity
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// FQDN - fully qualified domain name of host, resolved by DNS from kernel hostname.
+// Kernel hostname is used if it cannot be resolved
+type FQDN struct{}
+
+// Identity - implements Provider
+func (FQDN) Identity() (Identity, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Hostname: fqdn(hostname)}, nil
+}
+
+func fqdn(hostname string) string {
+	if strings.Contains(hostname, ".") {
+		return hostname
+	}
+	if cname, err := net.LookupCNAME(hostname); err == nil && cname != "" && cname != hostname+"." {
+		return strings.TrimSuffix(cname, ".")
+	}
+	addrs, err := net.LookupHost(hostname)
+	if err != nil {
+		return hostname
+	}
+	for _, a := range addrs {
+		names, err := net.LookupAddr(a)
+		if err != nil {
+			continue
+		}
+		for _, n := range names {
+			if n = strings.TrimSuffix(n, "."); strings.HasPrefix(n, hostname+".") {
+				return n
+			}
+		}
+	}
+	return hostname
+}
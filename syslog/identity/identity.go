@@ -0,0 +1,79 @@
+package identity
+
+import (
+	"os"
+
+	"slogger/syslog/format"
+)
+
+// Identity - values of HOSTNAME, APP-NAME and PROCID syslog fields and
+// optional metadata sent as structured data. Empty values are set by writer
+type Identity struct {
+	Hostname string
+	AppName  string
+	ProcID   string
+	Fields   format.Fields
+}
+
+// Provider - source of host identity
+type Provider interface {
+	Identity() (Identity, error)
+}
+
+// ProviderFunc - adapter to use ordinary functions as Provider
+type ProviderFunc func() (Identity, error)
+
+// Identity - calls f()
+func (f ProviderFunc) Identity() (Identity, error) {
+	return f()
+}
+
+// Static - fixed identity override
+type Static Identity
+
+// Identity - implements Provider
+func (s Static) Identity() (Identity, error) {
+	return Identity(s), nil
+}
+
+// OS - hostname reported by kernel, like log/syslog does
+type OS struct{}
+
+// Identity - implements Provider
+func (OS) Identity() (Identity, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Hostname: hostname}, nil
+}
+
+// Chain - merges identities of providers, non empty values of later providers override
+// values of earlier ones. Fields are merged the same way
+func Chain(providers ...Provider) Provider {
+	return ProviderFunc(func() (Identity, error) {
+		var res Identity
+		for _, p := range providers {
+			id, err := p.Identity()
+			if err != nil {
+				return Identity{}, err
+			}
+			if id.Hostname != "" {
+				res.Hostname = id.Hostname
+			}
+			if id.AppName != "" {
+				res.AppName = id.AppName
+			}
+			if id.ProcID != "" {
+				res.ProcID = id.ProcID
+			}
+			for k, v := range id.Fields {
+				if res.Fields == nil {
+					res.Fields = make(format.Fields)
+				}
+				res.Fields[k] = v
+			}
+		}
+		return res, nil
+	})
+}
@@ -0,0 +1,137 @@
+package identity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"slogger/syslog/format"
+)
+
+const testContainerID = "3f4ae5f1b0c8a1d5e6f7081920a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3"
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatalf("cannot write %s: %v", p, err)
+	}
+	return p
+}
+
+func TestStatic(t *testing.T) {
+	id, err := Static{Hostname: "h", AppName: "a", ProcID: "p"}.Identity()
+	if err != nil || id.Hostname != "h" || id.AppName != "a" || id.ProcID != "p" {
+		t.Errorf("unexpected identity: %+v, %v", id, err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	p := Chain(
+		Static{Hostname: "h1", AppName: "a1", Fields: format.Fields{"k1": "v1", "k2": "v2"}},
+		Static{},
+		Static{Hostname: "h2", Fields: format.Fields{"k2": "v3"}},
+	)
+	id, err := p.Identity()
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if id.Hostname != "h2" || id.AppName != "a1" || id.Fields["k1"] != "v1" || id.Fields["k2"] != "v3" {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, tc := range map[string]struct {
+		cgroup, mountinfo string
+		expect            string
+	}{
+		"docker": {
+			cgroup: "12:pids:/docker/" + testContainerID + "\n1:name=systemd:/docker/" + testContainerID + "\n",
+			expect: testContainerID,
+		},
+		"containerd": {
+			cgroup: "1:cpu:/kubepods/burstable/pod1/cri-containerd-" + testContainerID + ".scope\n",
+			expect: testContainerID,
+		},
+		"cgroup v2": {
+			cgroup: "0::/\n",
+			mountinfo: "100 90 0:50 / / rw - overlay overlay rw\n" +
+				"200 100 259:1 /var/lib/docker/containers/" + testContainerID + "/hostname /etc/hostname rw - ext4 /dev/root rw\n",
+			expect: testContainerID,
+		},
+		"host": {
+			cgroup:    "0::/user.slice/user-1000.slice/session-1.scope\n",
+			mountinfo: "100 90 0:50 / / rw - ext4 /dev/root rw\n",
+		},
+	} {
+		c := Container{
+			CgroupPath:    writeTestFile(t, dir, "cgroup", tc.cgroup),
+			MountinfoPath: writeTestFile(t, dir, "mountinfo", tc.mountinfo),
+		}
+		id, err := c.Identity()
+		if err != nil {
+			t.Errorf("%s: expect no error, got: %v", name, err)
+			continue
+		}
+		if tc.expect == "" {
+			if id.Hostname != "" || id.Fields != nil {
+				t.Errorf("%s: expect empty identity, got: %+v", name, id)
+			}
+			continue
+		}
+		if id.Hostname != tc.expect[:containerIDShortLen] || id.Fields[FieldContainerID] != tc.expect {
+			t.Errorf("%s: unexpected identity: %+v", name, id)
+		}
+	}
+
+	id, err := Container{CgroupPath: filepath.Join(dir, "none"), MountinfoPath: filepath.Join(dir, "none")}.Identity()
+	if err != nil || id.Hostname != "" {
+		t.Errorf("expect empty identity without files, got: %+v, %v", id, err)
+	}
+}
+
+func TestKubernetes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := map[string]string{"TEST_POD": "pod-env", "TEST_NS": "ns-env", "TEST_NODE": "node-env"}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	k := Kubernetes{PodEnv: "TEST_POD", NamespaceEnv: "TEST_NS", NodeEnv: "TEST_NODE"}
+
+	id, err := k.Identity()
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if id.Hostname != "pod-env" || id.Fields[FieldPod] != "pod-env" ||
+		id.Fields[FieldNamespace] != "ns-env" || id.Fields[FieldNode] != "node-env" {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+
+	writeTestFile(t, dir, DefaultPodFile, "pod-file\n")
+	k.Dir = dir
+	id, err = k.Identity()
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if id.Hostname != "pod-file" || id.Fields[FieldNamespace] != "ns-env" {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+
+	id, err = Kubernetes{PodEnv: "TEST_NO_POD"}.Identity()
+	if err != nil || id.Hostname != "" || id.Fields != nil {
+		t.Errorf("expect empty identity outside pod, got: %+v, %v", id, err)
+	}
+}
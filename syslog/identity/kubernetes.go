@@ -0,0 +1,92 @@
+package identity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"slogger/syslog/format"
+)
+
+// Default downward API environment variables
+const (
+	DefaultPodEnv       = "POD_NAME"
+	DefaultNamespaceEnv = "POD_NAMESPACE"
+	DefaultNodeEnv      = "NODE_NAME"
+)
+
+// Default names of downward API volume files
+const (
+	DefaultPodFile       = "podname"
+	DefaultNamespaceFile = "namespace"
+	DefaultNodeFile      = "nodename"
+)
+
+// Kubernetes metadata fields
+const (
+	FieldPod       = "k8s_pod"
+	FieldNamespace = "k8s_namespace"
+	FieldNode      = "k8s_node"
+)
+
+// Kubernetes - pod, namespace and node from downward API environment variables or files.
+// Sets hostname to pod name and Field* fields
+type Kubernetes struct {
+	// Dir - downward API volume mount path. If set, files are read before environment
+	Dir string
+
+	// Environment variables, Default*Env if empty
+	PodEnv, NamespaceEnv, NodeEnv string
+	// Files in Dir, Default*File if empty
+	PodFile, NamespaceFile, NodeFile string
+}
+
+// Identity - implements Provider. Returns empty identity if pod name is unknown
+func (k Kubernetes) Identity() (Identity, error) {
+	pod, err := k.value(k.PodFile, DefaultPodFile, k.PodEnv, DefaultPodEnv)
+	if err != nil || pod == "" {
+		return Identity{}, err
+	}
+	namespace, err := k.value(k.NamespaceFile, DefaultNamespaceFile, k.NamespaceEnv, DefaultNamespaceEnv)
+	if err != nil {
+		return Identity{}, err
+	}
+	node, err := k.value(k.NodeFile, DefaultNodeFile, k.NodeEnv, DefaultNodeEnv)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	fields := format.Fields{FieldPod: pod}
+	if namespace != "" {
+		fields[FieldNamespace] = namespace
+	}
+	if node != "" {
+		fields[FieldNode] = node
+	}
+	return Identity{
+		Hostname: pod,
+		Fields:   fields,
+	}, nil
+}
+
+// value - returns trimmed content of downward API file, environment variable otherwise
+func (k Kubernetes) value(file, defaultFile, env, defaultEnv string) (string, error) {
+	if k.Dir != "" {
+		if file == "" {
+			file = defaultFile
+		}
+		b, err := ioutil.ReadFile(filepath.Join(k.Dir, file))
+		if err == nil {
+			return strings.TrimSpace(string(b)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	if env == "" {
+		env = defaultEnv
+	}
+	return strings.TrimSpace(os.Getenv(env)), nil
+}
@@ -0,0 +1,59 @@
+package journald
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"log/syslog"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// readMemfd - reads entry from memfd passed with SCM_RIGHTS
+func readMemfd(oob []byte) ([]byte, error) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 {
+		return nil, errors.New("expect one control message")
+	}
+	fds, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(fds) != 1 {
+		return nil, errors.New("expect one descriptor")
+	}
+	f := os.NewFile(uintptr(fds[0]), "memfd")
+	defer f.Close()
+	// descriptor shares file offset with writer, journald reads memfd from start too
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(f)
+}
+
+func TestWriter_Memfd(t *testing.T) {
+	path, entries, stop := listenJournal(t)
+	defer stop()
+
+	w, err := Dial(path, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+
+	m := strings.Repeat("x", 512*1024)
+	if err := w.Info(m); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	e := waitEntry(t, entries)
+	if e["MESSAGE"] != m || e["PRIORITY"] != "6" {
+		t.Errorf("unexpected entry of large message: %d bytes, priority %q", len(e["MESSAGE"]), e["PRIORITY"])
+	}
+}
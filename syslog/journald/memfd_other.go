@@ -0,0 +1,13 @@
+// +build !linux
+
+package journald
+
+import (
+	"errors"
+	"net"
+)
+
+// sendMemfd - memfd is available on linux only
+func sendMemfd(conn *net.UnixConn, p []byte) error {
+	return errors.New("journald: entry too large")
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package journald
+
+import "errors"
+
+func readMemfd(oob []byte) ([]byte, error) {
+	return nil, errors.New("memfd is not supported")
+}
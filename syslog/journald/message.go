@@ -0,0 +1,85 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"slogger/syslog/format"
+)
+
+// Journal fields set by writer
+const (
+	FieldMessage          = "MESSAGE"
+	FieldPriority         = "PRIORITY"
+	FieldSyslogFacility   = "SYSLOG_FACILITY"
+	FieldSyslogIdentifier = "SYSLOG_IDENTIFIER"
+	FieldSyslogPID        = "SYSLOG_PID"
+	FieldCodeFile         = "CODE_FILE"
+	FieldCodeLine         = "CODE_LINE"
+)
+
+// FieldName - converts record field name to journal field name: uppercase letters, digits and
+// underscores, not starting with underscore or digit (e.g. "request-id" to "REQUEST_ID")
+func FieldName(name string) string {
+	b := []byte(strings.ToUpper(name))
+	for i, c := range b {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return strings.TrimLeft(string(b), "_0123456789")
+}
+
+// encode - builds native journal protocol datagram of record
+func encode(r *format.Record) []byte {
+	b := new(bytes.Buffer)
+	writeField(b, FieldMessage, strings.TrimSuffix(r.Message, "\n"))
+	writeField(b, FieldPriority, strconv.Itoa(int(r.Priority&0x07)))
+	writeField(b, FieldSyslogFacility, strconv.Itoa(int(r.Priority&0xf8)>>3))
+	if r.Tag != "" {
+		writeField(b, FieldSyslogIdentifier, r.Tag)
+	}
+	if r.ProcID != "" {
+		writeField(b, FieldSyslogPID, r.ProcID)
+	} else if r.PID > 0 {
+		writeField(b, FieldSyslogPID, strconv.Itoa(r.PID))
+	}
+	if i := strings.LastIndexByte(r.Caller, ':'); i > 0 {
+		writeField(b, FieldCodeFile, r.Caller[:i])
+		writeField(b, FieldCodeLine, r.Caller[i+1:])
+	}
+	for k, v := range r.Fields {
+		if k = FieldName(k); k != "" && !reservedField(k) {
+			writeField(b, k, fmt.Sprint(v))
+		}
+	}
+	return b.Bytes()
+}
+
+// reservedField - fields set by writer cannot be overridden by record fields
+func reservedField(k string) bool {
+	switch k {
+	case FieldMessage, FieldPriority, FieldSyslogFacility, FieldSyslogIdentifier, FieldSyslogPID:
+		return true
+	}
+	return false
+}
+
+// writeField - writes "KEY=value\n" or, for values with line breaks, binary-safe
+// "KEY\n<64-bit little endian length>value\n"
+func writeField(b *bytes.Buffer, k, v string) {
+	b.WriteString(k)
+	if !strings.ContainsRune(v, '\n') {
+		b.WriteByte('=')
+		b.WriteString(v)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(v)))
+	b.WriteString(v)
+	b.WriteByte('\n')
+}
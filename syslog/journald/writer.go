@@ -0,0 +1,179 @@
+package journald
+
+import (
+	"errors"
+	"log/syslog"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"slogger/syslog/format"
+)
+
+// DefaultSocket - native protocol socket of systemd-journald
+const DefaultSocket = "/run/systemd/journal/socket"
+
+const (
+	severityMask = 0x07
+	facilityMask = 0xf8
+)
+
+// Writer - systemd-journald writer using native protocol. Message, severity, tag, pid, caller
+// and fields are sent as journal fields, entries exceeding datagram size are passed as memfd.
+// It implements syslog.SyslogWriter and syslog.RecordWriter, so can be used as sender sink
+type Writer struct {
+	priority syslog.Priority
+	tag      string
+	path     string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// Dial - connects to journald socket at path, DefaultSocket if path is empty
+func Dial(path string, priority syslog.Priority, tag string) (*Writer, error) {
+	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
+		return nil, errors.New("journald: invalid priority")
+	}
+	if path == "" {
+		path = DefaultSocket
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+
+	w := &Writer{
+		priority: priority,
+		tag:      tag,
+		path:     path,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect makes a connection to the journald socket, so recreated socket is picked up.
+// It must be called with w.mu held.
+func (w *Writer) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	w.conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: w.path, Net: "unixgram"})
+	return err
+}
+
+// Close - closes connection
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.writeAndRetry(&format.Record{Priority: w.priority, Message: string(b)})
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - journal fields keep line breaks of multi-line messages (see writeField), so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - sends record as journal entry, fields are sent as journal fields (see FieldName)
+func (w *Writer) WriteRecord(r *format.Record) error {
+	_, err := w.writeAndRetry(r)
+	return err
+}
+
+func (w *Writer) writeAndRetry(r *format.Record) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rec := *r
+	rec.Priority = (w.priority & facilityMask) | (r.Priority & severityMask)
+	if rec.Tag == "" {
+		rec.Tag = w.tag
+	}
+	if rec.PID == 0 {
+		rec.PID = os.Getpid()
+	}
+
+	if w.conn != nil {
+		if n, err := w.write(&rec); err == nil {
+			return n, err
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	return w.write(&rec)
+}
+
+func (w *Writer) write(r *format.Record) (int, error) {
+	p := encode(r)
+	if _, err := w.conn.Write(p); err != nil {
+		if !isTooLarge(err) {
+			return 0, err
+		}
+		if err := sendMemfd(w.conn, p); err != nil {
+			return 0, err
+		}
+	}
+	return len(r.Message), nil
+}
+
+// isTooLarge - returns true if datagram exceeds socket limits (EMSGSIZE or ENOBUFS)
+func isTooLarge(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EMSGSIZE || err == syscall.ENOBUFS
+}
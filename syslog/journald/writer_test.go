@@ -0,0 +1,140 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// listenJournal - journald stand-in, returns parsed entries of received datagrams and memfds
+func listenJournal(t *testing.T) (string, <-chan map[string]string, func()) {
+	dir, err := ioutil.TempDir("", "journald")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("cannot listen: %v", err)
+	}
+
+	entries := make(chan map[string]string, 8)
+	go func() {
+		b := make([]byte, 64*1024)
+		oob := make([]byte, 1024)
+		for {
+			n, oobn, _, _, err := conn.ReadMsgUnix(b, oob)
+			if err != nil {
+				return
+			}
+			p := b[:n]
+			if oobn > 0 {
+				if p, err = readMemfd(oob[:oobn]); err != nil {
+					t.Errorf("cannot read memfd: %v", err)
+					continue
+				}
+			}
+			entries <- parseEntry(p)
+		}
+	}()
+
+	return path, entries, func() {
+		conn.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func parseEntry(p []byte) map[string]string {
+	e := map[string]string{}
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(p[:i])
+		p = p[i+1:]
+		if j := strings.IndexByte(line, '='); j >= 0 {
+			e[line[:j]] = line[j+1:]
+			continue
+		}
+		n := binary.LittleEndian.Uint64(p)
+		e[line] = string(p[8 : 8+n])
+		p = p[8+n+1:]
+	}
+	return e
+}
+
+func waitEntry(t *testing.T, entries <-chan map[string]string) map[string]string {
+	select {
+	case e := <-entries:
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("entry not received")
+	}
+	return nil
+}
+
+func TestWriter_WriteRecord(t *testing.T) {
+	path, entries, stop := listenJournal(t)
+	defer stop()
+
+	w, err := Dial(path, syslog.LOG_WARNING|syslog.LOG_LOCAL0, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+
+	err = w.WriteRecord(&format.Record{
+		Priority: syslog.LOG_ERR,
+		Message:  "first\nsecond",
+		Caller:   "app/main.go:42",
+		Fields:   format.Fields{"request-id": "abc", "_hidden": 1, "PRIORITY": 0},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	e := waitEntry(t, entries)
+	expect := map[string]string{
+		"MESSAGE":           "first\nsecond",
+		"PRIORITY":          "3",
+		"SYSLOG_FACILITY":   "16",
+		"SYSLOG_IDENTIFIER": "tag",
+		"CODE_FILE":         "app/main.go",
+		"CODE_LINE":         "42",
+		"REQUEST_ID":        "abc",
+		"HIDDEN":            "1",
+	}
+	for k, v := range expect {
+		if e[k] != v {
+			t.Errorf("expect %s=%q, got: %q", k, v, e[k])
+		}
+	}
+	if e["SYSLOG_PID"] == "" {
+		t.Errorf("expect SYSLOG_PID, got: %v", e)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for name, expect := range map[string]string{
+		"request_id": "REQUEST_ID",
+		"k8s.pod":    "K8S_POD",
+		"_source":    "SOURCE",
+		"1st":        "ST",
+		"__":         "",
+	} {
+		if n := FieldName(name); n != expect {
+			t.Errorf("expect %q for %q, got: %q", expect, name, n)
+		}
+	}
+}
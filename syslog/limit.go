@@ -0,0 +1,284 @@
+package syslog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"slogger/syslog/format"
+	"slogger/syslog/gelf"
+)
+
+// SizePolicy - what to do with messages longer than maximum size of transport
+type SizePolicy int
+
+const (
+	// SizePolicyTruncate - cut message and append TruncateMarker
+	SizePolicyTruncate SizePolicy = iota
+	// SizePolicySplit - send message as several continuation records with common correlation ID
+	SizePolicySplit
+)
+
+// Default maximum sizes of record formatted with sender formatter (header, structured data and message).
+// Writers with their own encoding (GELF, Loki...) are limited by size of the same formatted record
+const (
+	// MaxMessageSizeUDP - RFC 5426: receivers SHOULD accept datagrams up to 2048 octets
+	MaxMessageSizeUDP = 2048
+	// MaxMessageSizeTCP - RFC 5425/6587: receivers SHOULD accept messages up to 8192 octets
+	MaxMessageSizeTCP = 8192
+	// MaxMessageSizeTLS - RFC 5425: receivers SHOULD accept messages up to 8192 octets
+	MaxMessageSizeTLS = 8192
+	// MaxMessageSizeUnix - rsyslog imuxsock and syslog-ng default maximum message size (8k)
+	MaxMessageSizeUnix = 8192
+	// MaxMessageSizeRELP - rsyslog imrelp default maxDataSize (global maxMessageSize, 8k)
+	MaxMessageSizeRELP = 8192
+	// MaxMessageSizeGELFUDP - GELF udp message must fit 128 chunks of default size
+	MaxMessageSizeGELFUDP = gelf.MaxMessageSizeUDP
+	// MaxMessageSizeGELFTCP - Graylog GELF tcp input default maximum message size
+	MaxMessageSizeGELFTCP = gelf.MaxMessageSizeTCP
+)
+
+// TruncateMarker - appended to truncated messages
+const TruncateMarker = "...[truncated]"
+
+// Fields set on continuation records
+const (
+	FieldSplitID    = "split_id"
+	FieldSplitPart  = "split_part"
+	FieldSplitParts = "split_parts"
+)
+
+// Stats - sender counters
+type Stats struct {
+	// Truncated - count of truncated messages
+	Truncated uint64
+	// Split - count of messages sent as continuation records
+	Split uint64
+	// SplitParts - total count of continuation records
+	SplitParts uint64
+	// Failovers - count of switches to next endpoint after dial or write failures
+	Failovers uint64
+	// Failbacks - count of switches back to recovered preferred endpoint
+	Failbacks uint64
+	// Connects - count of connections made to endpoints (batches reuse connection until
+	// it is broken, idle or endpoint is switched)
+	Connects uint64
+	// Dropped - count of records dropped on Close because no endpoint accepted them, or dropped
+	// by writer which cannot send them at all (see format.ErrDropped)
+	Dropped uint64
+}
+
+// WithMaxMessageSize - override default maximum message size of transport, 0 - no limit
+func WithMaxMessageSize(n int) Option {
+	return func(s *syslog) {
+		s.maxMessageSize = n
+		s.maxMessageSizeSet = true
+	}
+}
+
+// WithSizePolicy - set what to do with too long messages, SizePolicyTruncate by default
+func WithSizePolicy(p SizePolicy) Option {
+	return func(s *syslog) {
+		s.sizePolicy = p
+	}
+}
+
+// defaultMaxMessageSize - returns maximum message size of protocol (see SizeLimitDialer), 0 - no limit
+func defaultMaxMessageSize(syslogProtocol string) int {
+	if d, ok := lookupTransport(syslogProtocol).(SizeLimitDialer); ok {
+		return d.MaxMessageSize()
+	}
+	return 0
+}
+
+// limitRecord - returns records to send instead of r, so formatted records fit maximum message size
+// of protocol of dialed endpoint (or size set with WithMaxMessageSize)
+func (s *syslog) limitRecord(protocol string, r *bufferRecord) []*bufferRecord {
+	max := s.maxMessageSize
+	if !s.maxMessageSizeSet {
+		max = defaultMaxMessageSize(protocol)
+	}
+	if max <= 0 || s.formattedSize(r) <= max {
+		return []*bufferRecord{r}
+	}
+
+	if s.sizePolicy == SizePolicySplit {
+		if recs := s.splitRecord(r, max); recs != nil {
+			return recs
+		}
+	}
+
+	atomic.AddUint64(&s.stats.Truncated, 1)
+	t := *r
+	// escaping may expand message, so budget is reduced until formatted record fits
+	budget := max - s.overhead(r)
+	for {
+		t.value = truncateMessage(r.value, budget)
+		excess := s.formattedSize(&t) - max
+		if excess <= 0 || budget <= 0 {
+			break
+		}
+		budget -= shrink(excess, budget)
+	}
+	return []*bufferRecord{&t}
+}
+
+// formattedSize - returns size of r formatted by sender formatter with header set as writers set it
+func (s *syslog) formattedSize(r *bufferRecord) int {
+	rec := s.formatRecord(r)
+	rec.Priority = (dialPriority & facilityMask) | (rec.Priority & severityMask)
+	if rec.Tag == "" {
+		rec.Tag = s.syslogTag
+	}
+	if rec.Hostname == "" {
+		rec.Hostname = s.hostname
+	}
+	if rec.PID == 0 && rec.ProcID == "" {
+		rec.PID = os.Getpid()
+	}
+	return len(s.formatter.Format(rec))
+}
+
+// overhead - returns formatted size of r without message
+func (s *syslog) overhead(r *bufferRecord) int {
+	e := *r
+	e.value = ""
+	return s.formattedSize(&e)
+}
+
+// shrink - returns how much to reduce budget of message text by excess of formatted record,
+// at least 1/8 of budget so fitting converges fast with expanding escaping
+func shrink(excess, budget int) int {
+	if d := budget / 8; d > excess {
+		return d
+	}
+	return excess
+}
+
+// truncateMessage - returns v cut to n bytes with TruncateMarker
+func truncateMessage(v string, n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(TruncateMarker) {
+		return format.TruncateUTF8(v, n-len(TruncateMarker)) + TruncateMarker
+	}
+	return format.TruncateUTF8(v, n)
+}
+
+// splitRecord - splits r to continuation records "[<id> <part>/<parts>] <text>" fitting max when
+// formatted. Returns nil if max is too small to fit prefix
+func (s *syslog) splitRecord(r *bufferRecord, max int) []*bufferRecord {
+	id := splitID()
+	size := max - s.overhead(splitPartRecord(r, id, 1, 1, ""))
+	for size > 0 {
+		recs := splitParts(r, id, size)
+		if recs == nil {
+			return nil
+		}
+		excess := 0
+		for _, c := range recs {
+			if e := s.formattedSize(c) - max; e > excess {
+				excess = e
+			}
+		}
+		if excess == 0 {
+			atomic.AddUint64(&s.stats.Split, 1)
+			atomic.AddUint64(&s.stats.SplitParts, uint64(len(recs)))
+			return recs
+		}
+		size -= shrink(excess, size)
+	}
+	return nil
+}
+
+// splitParts - splits text of r to parts of at most size bytes with prefixes.
+// Returns nil if size is too small to fit prefix
+func splitParts(r *bufferRecord, id string, size int) []*bufferRecord {
+	// parts count affects prefix length, so recount until it is stable
+	parts := 1
+	for {
+		prefixLen := len(splitPrefix(id, parts, parts))
+		if prefixLen >= size {
+			return nil
+		}
+		n := countParts(r.value, size-prefixLen)
+		if n <= parts {
+			break
+		}
+		parts = n
+	}
+
+	recs := make([]*bufferRecord, 0, parts)
+	v := r.value
+	for i := 1; len(v) > 0; i++ {
+		prefix := splitPrefix(id, i, parts)
+		part := splitPart(v, size-len(prefix))
+		v = v[len(part):]
+		recs = append(recs, splitPartRecord(r, id, i, parts, prefix+part))
+	}
+	return recs
+}
+
+// splitPartRecord - returns continuation record of r with text v
+func splitPartRecord(r *bufferRecord, id string, part, parts int, v string) *bufferRecord {
+	c := *r
+	c.value = v
+	c.ctx = ContextWithFields(r.ctx, format.Fields{
+		FieldSplitID:    id,
+		FieldSplitPart:  part,
+		FieldSplitParts: parts,
+	})
+	return &c
+}
+
+// Stats - returns sender counters
+func (s *syslog) Stats() Stats {
+	return Stats{
+		Truncated:  atomic.LoadUint64(&s.stats.Truncated),
+		Split:      atomic.LoadUint64(&s.stats.Split),
+		SplitParts: atomic.LoadUint64(&s.stats.SplitParts),
+		Failovers:  atomic.LoadUint64(&s.stats.Failovers),
+		Failbacks:  atomic.LoadUint64(&s.stats.Failbacks),
+		Connects:   atomic.LoadUint64(&s.stats.Connects),
+		Dropped:    atomic.LoadUint64(&s.stats.Dropped),
+	}
+}
+
+func splitPrefix(id string, part, parts int) string {
+	return "[" + id + " " + strconv.Itoa(part) + "/" + strconv.Itoa(parts) + "] "
+}
+
+// countParts - returns count of parts of at most size bytes, v is split on UTF-8 boundaries
+func countParts(v string, size int) int {
+	n := 0
+	for len(v) > 0 {
+		v = v[len(splitPart(v, size)):]
+		n++
+	}
+	return n
+}
+
+// splitPart - returns next part of v of at most size bytes without breaking multibyte characters,
+// at least one character so splitting makes progress
+func splitPart(v string, size int) string {
+	if p := format.TruncateUTF8(v, size); p != "" || v == "" {
+		return p
+	}
+	_, n := utf8.DecodeRuneInString(v)
+	return v[:n]
+}
+
+// splitID - returns random correlation ID of continuation records
+func splitID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
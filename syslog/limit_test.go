@@ -0,0 +1,185 @@
+package syslog
+
+import (
+	"context"
+	"fmt"
+	slog "log/syslog"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/mock"
+)
+
+func newLimitTestSender(t *testing.T, protocol string, opts ...Option) (*syslog, *mock.RecordWriter) {
+	mockWriter := &mock.RecordWriter{}
+	s, err := New(context.Background(), protocol, "2", "3", 8, 100*time.Second, 8, opts...)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	sl := s.(*syslog)
+	sl.SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return mockWriter, true
+	})
+	return sl, mockWriter
+}
+
+func TestSyslog_limitTruncate(t *testing.T) {
+	for protocol, max := range map[string]int{
+		SyslogProtocolUDP:  MaxMessageSizeUDP,
+		SyslogProtocolTCP:  MaxMessageSizeTCP,
+		SyslogProtocolRELP: MaxMessageSizeRELP,
+	} {
+		s, mockWriter := newLimitTestSender(t, protocol)
+		s.Send(context.Background(), slog.LOG_ERR, "short")
+		s.Send(context.Background(), slog.LOG_ERR, strings.Repeat("я", max))
+		s.Close()
+
+		recs := mockWriter.Records()
+		if len(recs) != 2 {
+			t.Fatalf("%s: expect 2 records, got: %d", protocol, len(recs))
+		}
+		if recs[0].Message != "short" {
+			t.Errorf("%s: expect short message unchanged, got: %q", protocol, recs[0].Message)
+		}
+		m := recs[1].Message
+		if len(m) > max || !strings.HasSuffix(m, TruncateMarker) {
+			t.Errorf("%s: expect truncated message up to %d bytes, got %d bytes", protocol, max, len(m))
+		}
+		if !strings.HasPrefix(m, "я") || strings.ContainsRune(m, '�') {
+			t.Errorf("%s: expect message cut on UTF-8 boundary", protocol)
+		}
+		if st := s.Stats(); st.Truncated != 1 || st.Split != 0 {
+			t.Errorf("%s: unexpected stats: %+v", protocol, st)
+		}
+	}
+}
+
+func TestSyslog_limitSplit(t *testing.T) {
+	for protocol, max := range map[string]int{
+		SyslogProtocolUDP:  MaxMessageSizeUDP,
+		SyslogProtocolTCP:  MaxMessageSizeTCP,
+		SyslogProtocolRELP: MaxMessageSizeRELP,
+	} {
+		s, mockWriter := newLimitTestSender(t, protocol, WithSizePolicy(SizePolicySplit))
+		m := strings.Repeat("0123456789", max/4)
+		s.Send(context.Background(), slog.LOG_ERR, m)
+		s.Close()
+
+		recs := mockWriter.Records()
+		if len(recs) < 3 {
+			t.Fatalf("%s: expect at least 3 records, got: %d", protocol, len(recs))
+		}
+		id := recs[0].Fields[FieldSplitID]
+		joined := ""
+		for i, r := range recs {
+			if len(r.Message) > max {
+				t.Errorf("%s: part %d: expect up to %d bytes, got %d", protocol, i, max, len(r.Message))
+			}
+			if r.Fields[FieldSplitID] != id || r.Fields[FieldSplitPart] != i+1 || r.Fields[FieldSplitParts] != len(recs) {
+				t.Errorf("%s: part %d: unexpected fields: %v", protocol, i, r.Fields)
+			}
+			prefix := splitPrefix(id.(string), i+1, len(recs))
+			if !strings.HasPrefix(r.Message, prefix) {
+				t.Errorf("%s: part %d: expect prefix %q, got %q", protocol, i, prefix, r.Message[:len(prefix)])
+			}
+			joined += strings.TrimPrefix(r.Message, prefix)
+		}
+		if joined != m {
+			t.Errorf("%s: joined parts differ from message", protocol)
+		}
+		if st := s.Stats(); st.Split != 1 || st.SplitParts != uint64(len(recs)) || st.Truncated != 0 {
+			t.Errorf("%s: unexpected stats: %+v", protocol, st)
+		}
+	}
+}
+
+func TestSyslog_limitOverride(t *testing.T) {
+	s, mockWriter := newLimitTestSender(t, SyslogProtocolUDP, WithMaxMessageSize(0))
+	m := strings.Repeat("0", MaxMessageSizeUDP*2)
+	s.Send(context.Background(), slog.LOG_ERR, m)
+	s.Close()
+
+	if recs := mockWriter.Records(); len(recs) != 1 || recs[0].Message != m {
+		t.Errorf("expect message unchanged without limit")
+	}
+}
+
+func TestSyslog_limitEndpoint(t *testing.T) {
+	mockWriter := &mock.RecordWriter{}
+	s, err := New(context.Background(), SyslogProtocolUDP, "udp-host", "3", 8, 100*time.Second, 8,
+		WithEndpoints(Endpoint{Protocol: SyslogProtocolTCP, Addr: "tcp-host", Priority: 1}),
+		WithFailover(FailoverConfig{MaxFailures: 1}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	// batch is sent by test
+	s.Close()
+	sl := s.(*syslog)
+	sl.SetDialMethod(func(_ context.Context, _, addr, _ string) (SyslogWriter, bool) {
+		return mockWriter, addr == "tcp-host"
+	})
+	// fits tcp, but not udp limit
+	m := strings.Repeat("0", MaxMessageSizeUDP*2)
+	sl.toSyslogBulk(context.Background(), []*bufferRecord{{ctx: context.Background(), level: slog.LOG_ERR, value: m}})
+	sl.closeConn()
+
+	if recs := mockWriter.Records(); len(recs) != 1 || recs[0].Message != m {
+		t.Errorf("expect message sent to failover endpoint with its size limit")
+	}
+	if st := sl.Stats(); st.Truncated != 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestSyslog_limitFormatted(t *testing.T) {
+	fields := format.Fields{}
+	for i := 0; i < 20; i++ {
+		fields[fmt.Sprintf("field%02d", i)] = strings.Repeat(`"`, 20)
+	}
+	ctx := ContextWithFields(context.Background(), fields)
+	for _, policy := range []SizePolicy{SizePolicyTruncate, SizePolicySplit} {
+		srv := newTestServer(t, SyslogProtocolUDP, FramingNonTransparent)
+		s, err := New(context.Background(), SyslogProtocolUDP, srv.addr, "tag", 8, 10*time.Millisecond, 8,
+			WithFormatter(format.RFC5424{SDID: "meta@32473"}), WithSizePolicy(policy))
+		if err != nil {
+			t.Fatalf("cannot create syslog sender: %v", err)
+		}
+		// message fits limit alone, but not with escaped structured data
+		s.Send(ctx, slog.LOG_ERR, strings.Repeat("0", MaxMessageSizeUDP-300))
+		s.Close()
+		time.Sleep(20 * time.Millisecond)
+
+		msgs := srv.waitMessages(1)
+		if len(msgs) == 0 {
+			t.Fatalf("%d: expect datagrams received", policy)
+		}
+		for _, m := range msgs {
+			if len(m) > MaxMessageSizeUDP {
+				t.Errorf("%d: expect datagram up to %d bytes, got: %d", policy, MaxMessageSizeUDP, len(m))
+			}
+		}
+		if st := s.Stats(); st.Truncated+st.Split != 1 {
+			t.Errorf("%d: expect message limited, got: %+v", policy, st)
+		}
+		srv.close()
+	}
+}
+
+func Test_splitPart(t *testing.T) {
+	for _, tc := range []struct {
+		s      string
+		n      int
+		expect string
+	}{
+		{"abc", 5, "abc"},
+		{"aяb", 2, "a"},
+		{"яb", 1, "я"},
+		{"", 0, ""},
+	} {
+		if r := splitPart(tc.s, tc.n); r != tc.expect {
+			t.Errorf("splitPart(%q, %d): expect %q, got %q", tc.s, tc.n, tc.expect, r)
+		}
+	}
+}
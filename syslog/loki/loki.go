@@ -0,0 +1,306 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/httppush"
+)
+
+// PushPath - path of Loki push API, used if URL has no path
+const PushPath = "/loki/api/v1/push"
+
+// Labels set by writer
+const (
+	LabelTag      = "tag"
+	LabelSeverity = "severity"
+)
+
+// Retry defaults
+const (
+	DefaultMaxRetries = httppush.DefaultMaxRetries
+	DefaultMinBackoff = httppush.DefaultMinBackoff
+	DefaultMaxBackoff = httppush.DefaultMaxBackoff
+	DefaultTimeout    = httppush.DefaultTimeout
+)
+
+const severityMask = 0x07
+
+// Config - Loki push settings
+type Config struct {
+	// Labels - static stream labels (e.g. "env", "host")
+	Labels map[string]string
+	// LabelFields - record fields promoted to stream labels, other fields are appended to line as key=value
+	LabelFields []string
+	// Gzip - compress request body
+	Gzip bool
+	// TenantID - X-Scope-OrgID of multi-tenant Loki
+	TenantID string
+	// MaxRetries - retries of 429 and 5xx responses and network errors, DefaultMaxRetries if 0, -1 - no retries
+	MaxRetries int
+	// MinBackoff, MaxBackoff - exponential backoff between retries, DefaultMinBackoff and DefaultMaxBackoff if 0
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Client - HTTP client, shared client with DefaultTimeout if nil
+	Client *http.Client
+}
+
+// retry - retry settings of push requests
+func (c Config) retry() httppush.Retry {
+	return httppush.Retry{MaxRetries: c.MaxRetries, MinBackoff: c.MinBackoff, MaxBackoff: c.MaxBackoff}
+}
+
+// Writer - Loki push API writer. Batch of records is pushed with one request, grouped into
+// streams by labels. It implements syslog.SyslogWriter, syslog.RecordWriter and syslog.BatchWriter,
+// so can be used as sender sink
+type Writer struct {
+	priority syslog.Priority
+	tag      string
+	url      string
+	cfg      Config
+	ctx      context.Context
+}
+
+// New - creates writer to Loki at rawURL (e.g. "http://loki:3100")
+func New(rawURL string, priority syslog.Priority, tag string, cfg Config) (*Writer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("loki: unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = PushPath
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+
+	return &Writer{
+		priority: priority,
+		tag:      tag,
+		url:      u.String(),
+		cfg:      cfg,
+		ctx:      context.Background(),
+	}, nil
+}
+
+// SetContext - set context which interrupts waiting between push retries when done (e.g. when
+// sender is closed), context.Background() by default
+func (w *Writer) SetContext(ctx context.Context) {
+	w.ctx = ctx
+}
+
+// Close - does nothing, requests are not kept between batches
+func (w *Writer) Close() error {
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.WriteRecord(&format.Record{Priority: w.priority, Message: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - log lines of Loki keep line breaks of multi-line messages, so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - pushes one record
+func (w *Writer) WriteRecord(r *format.Record) error {
+	return w.WriteRecords([]*format.Record{r})
+}
+
+// WriteRecords - pushes records with one request
+func (w *Writer) WriteRecords(recs []*format.Record) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	body, err := w.encode(recs)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if w.cfg.Gzip {
+		header.Set("Content-Encoding", "gzip")
+	}
+	if w.cfg.TenantID != "" {
+		header.Set("X-Scope-OrgID", w.cfg.TenantID)
+	}
+	return httppush.Post(w.ctx, httppush.Request{Client: w.cfg.Client, URL: w.url, Header: header, Body: body}, w.cfg.retry())
+}
+
+// pushRequest - body of push API request
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type entry struct {
+	ts   time.Time
+	line string
+}
+
+// encode - groups records into streams by labels, entries of stream are ordered by time
+func (w *Writer) encode(recs []*format.Record) ([]byte, error) {
+	var (
+		keys    []string
+		labels  = map[string]map[string]string{}
+		entries = map[string][]entry{}
+	)
+	for _, r := range recs {
+		l, line := w.labels(r)
+		k := labelsKey(l)
+		if _, ok := labels[k]; !ok {
+			labels[k] = l
+			keys = append(keys, k)
+		}
+		ts := r.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		entries[k] = append(entries[k], entry{ts: ts, line: line})
+	}
+
+	req := pushRequest{Streams: make([]stream, 0, len(keys))}
+	for _, k := range keys {
+		es := entries[k]
+		sort.SliceStable(es, func(i, j int) bool {
+			return es[i].ts.Before(es[j].ts)
+		})
+		s := stream{Stream: labels[k], Values: make([][2]string, len(es))}
+		for i, e := range es {
+			s.Values[i] = [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+		}
+		req.Streams = append(req.Streams, s)
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil || !w.cfg.Gzip {
+		return b, err
+	}
+	return httppush.Gzip(b)
+}
+
+// labels - returns stream labels of record and log line: message with fields which are not labels
+func (w *Writer) labels(r *format.Record) (map[string]string, string) {
+	tag := r.Tag
+	if tag == "" {
+		tag = w.tag
+	}
+	l := make(map[string]string, len(w.cfg.Labels)+len(w.cfg.LabelFields)+2)
+	for k, v := range w.cfg.Labels {
+		l[k] = v
+	}
+	l[LabelTag] = tag
+	l[LabelSeverity] = format.SeverityName(r.Priority & severityMask)
+
+	var rest []string
+	for k := range r.Fields {
+		if !w.isLabelField(k) {
+			rest = append(rest, k)
+		}
+	}
+	for _, k := range w.cfg.LabelFields {
+		if v, ok := r.Fields[k]; ok {
+			l[LabelName(k)] = fmt.Sprint(v)
+		}
+	}
+	sort.Strings(rest)
+
+	line := strings.TrimSuffix(r.Message, "\n")
+	for _, k := range rest {
+		v := fmt.Sprint(r.Fields[k])
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		line += " " + k + "=" + v
+	}
+	if r.Caller != "" {
+		line += " caller=" + r.Caller
+	}
+	return l, line
+}
+
+// LabelName - replaces characters not allowed in Loki label names with underscore (e.g. "k8s.pod" to "k8s_pod")
+func LabelName(k string) string {
+	b := []byte(k)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func (w *Writer) isLabelField(k string) bool {
+	for _, f := range w.cfg.LabelFields {
+		if f == k {
+			return true
+		}
+	}
+	return false
+}
+
+// labelsKey - returns label set as Loki stream selector, e.g. {severity="err",tag="app"}
+func labelsKey(l map[string]string) string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + strconv.Quote(l[k])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
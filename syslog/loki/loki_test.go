@@ -0,0 +1,120 @@
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/syslog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// newTestServer - Loki stand-in, responds with statuses in turn and then 204
+func newTestServer(t *testing.T, statuses ...int) (*httptest.Server, <-chan pushRequest, *int32) {
+	reqs := make(chan pushRequest, 8)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		if r.URL.Path != PushPath || r.Header.Get("X-Scope-OrgID") != "tenant" {
+			t.Errorf("unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("cannot read gzip: %v", err)
+				return
+			}
+			body = zr
+		}
+		var req pushRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			t.Errorf("cannot decode request: %v", err)
+		}
+		reqs <- req
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return srv, reqs, &calls
+}
+
+func TestWriter_WriteRecords(t *testing.T) {
+	srv, reqs, _ := newTestServer(t)
+	defer srv.Close()
+
+	w, err := New(srv.URL, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{
+		Labels:      map[string]string{"env": "test"},
+		LabelFields: []string{"k8s.pod"},
+		Gzip:        true,
+		TenantID:    "tenant",
+	})
+	if err != nil {
+		t.Fatalf("cannot create writer: %v", err)
+	}
+	ts := time.Unix(1562769255, 0)
+	err = w.WriteRecords([]*format.Record{
+		{Priority: syslog.LOG_ERR, Timestamp: ts.Add(time.Second), Message: "second", Fields: format.Fields{"k8s.pod": "web-1", "user": "john doe"}},
+		{Priority: syslog.LOG_ERR, Timestamp: ts, Message: "first", Fields: format.Fields{"k8s.pod": "web-1"}},
+		{Priority: syslog.LOG_INFO, Timestamp: ts, Message: "info"},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	req := <-reqs
+	if len(req.Streams) != 2 {
+		t.Fatalf("expect 2 streams, got: %+v", req.Streams)
+	}
+	s := req.Streams[0]
+	if s.Stream["env"] != "test" || s.Stream["tag"] != "app" || s.Stream["severity"] != "err" || s.Stream["k8s_pod"] != "web-1" {
+		t.Errorf("unexpected labels: %v", s.Stream)
+	}
+	if len(s.Values) != 2 || s.Values[0] != [2]string{"1562769255000000000", "first"} ||
+		s.Values[1][1] != `second user="john doe"` {
+		t.Errorf("unexpected values: %v", s.Values)
+	}
+	if req.Streams[1].Stream["severity"] != "info" {
+		t.Errorf("unexpected labels: %v", req.Streams[1].Stream)
+	}
+}
+
+func TestWriter_Retry(t *testing.T) {
+	srv, reqs, calls := newTestServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	w, err := New(srv.URL, syslog.LOG_DAEMON, "app", Config{TenantID: "tenant", MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("cannot create writer: %v", err)
+	}
+	if err := w.Err("message"); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	<-reqs
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Errorf("expect 3 requests, got: %d", n)
+	}
+}
+
+func TestWriter_NoRetry(t *testing.T) {
+	srv, _, calls := newTestServer(t, http.StatusBadRequest)
+	defer srv.Close()
+
+	w, err := New(srv.URL, syslog.LOG_DAEMON, "app", Config{MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("cannot create writer: %v", err)
+	}
+	if err := w.Err("message"); err == nil {
+		t.Errorf("expect error of bad request, got no error")
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("expect 1 request, got: %d", n)
+	}
+}
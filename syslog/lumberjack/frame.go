@@ -0,0 +1,82 @@
+package lumberjack
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Lumberjack v2 frame types
+const (
+	Version         byte = '2'
+	FrameWindow     byte = 'W'
+	FrameJSON       byte = 'J'
+	FrameCompressed byte = 'C'
+	FrameAck        byte = 'A'
+)
+
+// appendHeader - appends version, frame type and uint32 value
+func appendHeader(b []byte, frame byte, n uint32) []byte {
+	b = append(b, Version, frame, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], n)
+	return b
+}
+
+// appendJSON - appends JSON data frame of event with sequence number seq
+func appendJSON(b []byte, seq uint32, event []byte) []byte {
+	b = appendHeader(b, FrameJSON, seq)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], uint32(len(event)))
+	return append(b, event...)
+}
+
+// compressFrames - returns compressed frame of frames with zlib level
+func compressFrames(frames []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(frames); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return append(appendHeader(nil, FrameCompressed, uint32(buf.Len())), buf.Bytes()...), nil
+}
+
+// readHeader - reads version and frame type, returns error of unknown version
+func readHeader(r io.Reader) (byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return 0, err
+	}
+	if h[0] != Version {
+		return 0, fmt.Errorf("lumberjack: unsupported protocol version %q", h[0])
+	}
+	return h[1], nil
+}
+
+// readUint32 - reads big endian uint32 of frame
+func readUint32(r io.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// readAck - reads ACK frame, returns acknowledged sequence number
+func readAck(r io.Reader) (uint32, error) {
+	frame, err := readHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	if frame != FrameAck {
+		return 0, fmt.Errorf("lumberjack: unexpected frame %q", frame)
+	}
+	return readUint32(r)
+}
@@ -0,0 +1,242 @@
+// Package lumberjacktest provides in-process Lumberjack v2 (Logstash beats input) server for tests
+package lumberjacktest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+)
+
+// Server - Lumberjack v2 server which stores and acknowledges all events.
+// Plain and compressed JSON data frames are accepted
+type Server struct {
+	Addr string
+
+	ln          net.Listener
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	events      []map[string]interface{}
+	windows     int
+	partialAcks bool
+	failAfter   int
+	conns       map[net.Conn]struct{}
+	closed      bool
+}
+
+// NewServer - starts server on random local port
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return Serve(ln), nil
+}
+
+// Serve - starts server on listener ln (e.g. TLS listener)
+func Serve(ln net.Listener) *Server {
+	s := &Server{
+		Addr:      ln.Addr().String(),
+		ln:        ln,
+		failAfter: -1,
+		conns:     make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// Events - returns received (and acknowledged) events
+func (s *Server) Events() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]map[string]interface{}(nil), s.events...)
+}
+
+// Windows - returns count of received windows
+func (s *Server) Windows() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.windows
+}
+
+// PartialAcks - acknowledge every event of window instead of last one only, like Logstash does
+// while window is processed
+func (s *Server) PartialAcks(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partialAcks = on
+}
+
+// FailAfter - in next window, store and acknowledge first n events, then close connection
+func (s *Server) FailAfter(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failAfter = n
+}
+
+// Close - stops server and closes all connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	err := s.ln.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	r := bufio.NewReader(c)
+	for {
+		frame, n, err := readHeader(r)
+		if err != nil || frame != 'W' {
+			return
+		}
+		events, err := readEvents(r, int(n))
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.windows++
+		failAfter := s.failAfter
+		s.failAfter = -1
+		partial := s.partialAcks
+		if failAfter >= 0 && failAfter < len(events) {
+			events = events[:failAfter]
+		}
+		s.events = append(s.events, events...)
+		s.mu.Unlock()
+
+		for seq := 1; seq <= len(events); seq++ {
+			if !partial && seq < len(events) {
+				continue
+			}
+			if err := writeAck(c, uint32(seq)); err != nil {
+				return
+			}
+		}
+		if failAfter >= 0 && failAfter < int(n) {
+			return
+		}
+	}
+}
+
+// readEvents - reads n JSON events of window from plain or compressed data frames
+func readEvents(r io.Reader, n int) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	for len(events) < n {
+		frame, v, err := readHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		switch frame {
+		case 'J':
+			e, err := readJSON(r)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+		case 'C':
+			zr, err := zlib.NewReader(io.LimitReader(r, int64(v)))
+			if err != nil {
+				return nil, err
+			}
+			data, err := ioutil.ReadAll(zr)
+			if err != nil {
+				return nil, err
+			}
+			dr := bytes.NewReader(data)
+			for dr.Len() > 0 {
+				if frame, _, err = readHeader(dr); err != nil || frame != 'J' {
+					return nil, errors.New("lumberjacktest: data frame expected")
+				}
+				e, err := readJSON(dr)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, e)
+			}
+		default:
+			return nil, errors.New("lumberjacktest: unexpected frame")
+		}
+	}
+	return events, nil
+}
+
+// readHeader - reads version, frame type and uint32 which follows it (count, sequence or length)
+func readHeader(r io.Reader) (byte, uint32, error) {
+	var h [6]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return 0, 0, err
+	}
+	if h[0] != '2' {
+		return 0, 0, errors.New("lumberjacktest: unsupported version")
+	}
+	return h[1], binary.BigEndian.Uint32(h[2:]), nil
+}
+
+// readJSON - reads payload length and JSON event of data frame
+func readJSON(r io.Reader) (map[string]interface{}, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(l[:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	var e map[string]interface{}
+	return e, json.Unmarshal(b, &e)
+}
+
+func writeAck(w io.Writer, seq uint32) error {
+	b := []byte{'2', 'A', 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[2:], seq)
+	_, err := w.Write(b)
+	return err
+}
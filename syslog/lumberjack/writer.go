@@ -0,0 +1,337 @@
+// Package lumberjack implements Lumberjack v2 (Beats) protocol sink for Logstash beats input
+package lumberjack
+
+import (
+	"bufio"
+	"compress/zlib"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"slogger/syslog/conncheck"
+	"slogger/syslog/format"
+)
+
+const (
+	// DefaultTimeout - dial and write timeout, and time to wait for next ACK of window
+	DefaultTimeout = 30 * time.Second
+	// DefaultWindowSize - maximum events sent in one window
+	DefaultWindowSize = 1024
+)
+
+const (
+	severityMask = 0x07
+	facilityMask = 0xf8
+)
+
+// Config - Lumberjack sink settings
+type Config struct {
+	// WindowSize - maximum events sent before waiting for ACK, DefaultWindowSize if zero.
+	// Batch is split into windows of this size
+	WindowSize int
+	// Compression - zlib level of compressed frames (1-9), frames are not compressed if zero
+	Compression int
+	// Timeout - dial and write timeout, and time to wait for next ACK, DefaultTimeout if zero.
+	// Logstash sends partial ACKs while window is processed, so slow pipeline does not time out
+	Timeout time.Duration
+	// TLSConfig - connect over TLS if set
+	TLSConfig *tls.Config
+}
+
+// Writer - Lumberjack v2 writer. Batch of records is sent as windows of JSON data frames, every window
+// is written after previous one is acknowledged; not acknowledged part is sent again over new connection.
+// It implements syslog.SyslogWriter, syslog.RecordWriter and syslog.BatchWriter, so can be used as sender sink
+type Writer struct {
+	priority syslog.Priority
+	tag      string
+	hostname string
+	raddr    string
+	cfg      Config
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial - connects to Logstash beats input
+func Dial(raddr string, priority syslog.Priority, tag string, cfg Config) (*Writer, error) {
+	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
+		return nil, errors.New("lumberjack: invalid priority")
+	}
+	if cfg.Compression < 0 || cfg.Compression > zlib.BestCompression {
+		return nil, fmt.Errorf("lumberjack: invalid compression level %d", cfg.Compression)
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = DefaultWindowSize
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	hostname, _ := os.Hostname()
+
+	w := &Writer{
+		priority: priority,
+		tag:      tag,
+		hostname: hostname,
+		raddr:    raddr,
+		cfg:      cfg,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect makes a connection to the beats input.
+// It must be called with w.mu held.
+func (w *Writer) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	d := &net.Dialer{Timeout: w.cfg.Timeout}
+	var conn net.Conn
+	if w.cfg.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(d, "tcp", w.raddr, w.cfg.TLSConfig)
+	} else {
+		conn, err = d.Dial("tcp", w.raddr)
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	w.r = bufio.NewReader(conn)
+	if w.hostname == "" {
+		w.hostname = conn.LocalAddr().String()
+	}
+	return nil
+}
+
+// CheckConn - checks that connection kept between batches is not broken (e.g. closed by server)
+func (w *Writer) CheckConn() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return errors.New("lumberjack: not connected")
+	}
+	return conncheck.ReadProbe(w.conn)
+}
+
+// Close - closes connection
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.WriteRecord(&format.Record{Priority: w.priority, Message: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - message field of beats events keeps line breaks of multi-line messages, so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - sends one record
+func (w *Writer) WriteRecord(r *format.Record) error {
+	return w.WriteRecords([]*format.Record{r})
+}
+
+// WriteRecords - sends records in windows of WindowSize events, returns after all events are acknowledged
+func (w *Writer) WriteRecords(recs []*format.Record) error {
+	now := time.Now()
+	events := make([][]byte, 0, len(recs))
+	for _, r := range recs {
+		e, err := w.encode(r, now)
+		if err != nil {
+			return err
+		}
+		events = append(events, e)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(events) > 0 {
+		n := len(events)
+		if n > w.cfg.WindowSize {
+			n = w.cfg.WindowSize
+		}
+		if err := w.sendAndRetry(events[:n]); err != nil {
+			return err
+		}
+		events = events[n:]
+	}
+	return nil
+}
+
+// sendAndRetry sends window, not acknowledged events are sent again over new connection.
+// It must be called with w.mu held.
+func (w *Writer) sendAndRetry(events [][]byte) error {
+	if w.conn != nil {
+		acked, err := w.send(events)
+		if err == nil {
+			return nil
+		}
+		events = events[acked:]
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	_, err := w.send(events)
+	return err
+}
+
+// send writes window and waits for ACK of its last event, returns count of acknowledged events.
+// It must be called with w.mu held.
+func (w *Writer) send(events [][]byte) (int, error) {
+	frames := appendHeader(nil, FrameWindow, uint32(len(events)))
+	data := make([]byte, 0, 64*len(events))
+	for i, e := range events {
+		data = appendJSON(data, uint32(i+1), e)
+	}
+	if w.cfg.Compression > 0 {
+		c, err := compressFrames(data, w.cfg.Compression)
+		if err != nil {
+			return 0, err
+		}
+		data = c
+	}
+	frames = append(frames, data...)
+
+	w.conn.SetWriteDeadline(time.Now().Add(w.cfg.Timeout))
+	if _, err := w.conn.Write(frames); err != nil {
+		return 0, err
+	}
+
+	defer w.conn.SetReadDeadline(time.Time{})
+	acked := 0
+	for acked < len(events) {
+		w.conn.SetReadDeadline(time.Now().Add(w.cfg.Timeout))
+		seq, err := readAck(w.r)
+		if err != nil {
+			return acked, err
+		}
+		if int(seq) > len(events) {
+			return acked, fmt.Errorf("lumberjack: unexpected ACK %d of window %d", seq, len(events))
+		}
+		if int(seq) > acked {
+			acked = int(seq)
+		}
+	}
+	return acked, nil
+}
+
+// encode - returns JSON event of record in Beats (ECS) layout, fields are added at top level
+func (w *Writer) encode(r *format.Record, now time.Time) ([]byte, error) {
+	p := (w.priority & facilityMask) | (r.Priority & severityMask)
+	ts := r.Timestamp
+	if ts.IsZero() {
+		ts = now
+	}
+	host := w.hostname
+	if r.Hostname != "" {
+		host = r.Hostname
+	}
+	tag := w.tag
+	if r.Tag != "" {
+		tag = r.Tag
+	}
+	process := map[string]interface{}{"name": tag}
+	switch {
+	case r.ProcID != "":
+		process["pid"] = r.ProcID
+	case r.PID > 0:
+		process["pid"] = r.PID
+	default:
+		process["pid"] = os.Getpid()
+	}
+	log := map[string]interface{}{
+		"level":  format.SeverityName(p),
+		"syslog": map[string]interface{}{"priority": int(p), "facility": map[string]interface{}{"code": int(p >> 3)}},
+	}
+	if i := strings.LastIndexByte(r.Caller, ':'); i > 0 {
+		line, _ := strconv.Atoi(r.Caller[i+1:])
+		log["origin"] = map[string]interface{}{"file": map[string]interface{}{"name": r.Caller[:i], "line": line}}
+	}
+
+	e := make(map[string]interface{}, len(r.Fields)+8)
+	for k, v := range r.Fields {
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprint(v)
+		}
+		e[k] = v
+	}
+	e["@timestamp"] = ts.UTC().Format(time.RFC3339Nano)
+	e["@metadata"] = map[string]interface{}{"beat": tag, "type": "_doc"}
+	e["message"] = r.Message
+	e["host"] = map[string]interface{}{"name": host}
+	e["process"] = process
+	e["log"] = log
+	if r.TraceID != "" {
+		e["trace"] = map[string]interface{}{"id": r.TraceID}
+	}
+	if r.SpanID != "" {
+		e["span"] = map[string]interface{}{"id": r.SpanID}
+	}
+	return json.Marshal(e)
+}
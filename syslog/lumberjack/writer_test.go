@@ -0,0 +1,158 @@
+package lumberjack
+
+import (
+	"crypto/tls"
+	"log/syslog"
+	"strconv"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/lumberjack/lumberjacktest"
+	"slogger/syslog/tlsconfig/tlstest"
+)
+
+func records(n int) []*format.Record {
+	recs := make([]*format.Record, n)
+	for i := range recs {
+		recs[i] = &format.Record{Priority: syslog.LOG_INFO, Message: "message " + strconv.Itoa(i)}
+	}
+	return recs
+}
+
+func TestWriter_WriteRecords(t *testing.T) {
+	srv, err := lumberjacktest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	w, err := Dial(srv.Addr, syslog.LOG_WARNING|syslog.LOG_LOCAL0, "app", Config{})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	err = w.WriteRecords([]*format.Record{{
+		Priority: syslog.LOG_ERR, Timestamp: ts, Message: "failed", Caller: "main.go:12",
+		Fields: format.Fields{"user": "bob", "ch": make(chan int)}, TraceID: "abc",
+	}})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	events := srv.Events()
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got: %d", len(events))
+	}
+	e := events[0]
+	if e["message"] != "failed" || e["user"] != "bob" {
+		t.Errorf("expect message and fields, got: %v", e)
+	}
+	if e["@timestamp"] != "2024-01-02T03:04:05.000006Z" {
+		t.Errorf("expect timestamp of record, got: %v", e["@timestamp"])
+	}
+	if s, _ := e["ch"].(string); s == "" {
+		t.Errorf("expect not JSON field formatted as string, got: %v", e["ch"])
+	}
+	log, _ := e["log"].(map[string]interface{})
+	if log["level"] != "err" {
+		t.Errorf("expect level err, got: %v", log["level"])
+	}
+	sl, _ := log["syslog"].(map[string]interface{})
+	if sl["priority"] != float64(syslog.LOG_LOCAL0|syslog.LOG_ERR) {
+		t.Errorf("expect priority of writer facility, got: %v", sl["priority"])
+	}
+	if trace, _ := e["trace"].(map[string]interface{}); trace["id"] != "abc" {
+		t.Errorf("expect trace id, got: %v", e["trace"])
+	}
+}
+
+func TestWriter_Windows(t *testing.T) {
+	srv, err := lumberjacktest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.PartialAcks(true)
+
+	w, err := Dial(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{WindowSize: 10, Compression: 3})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.WriteRecords(records(25)); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if n := srv.Windows(); n != 3 {
+		t.Errorf("expect 3 windows, got: %d", n)
+	}
+	events := srv.Events()
+	if len(events) != 25 {
+		t.Fatalf("expect 25 events, got: %d", len(events))
+	}
+	for i, e := range events {
+		if e["message"] != "message "+strconv.Itoa(i) {
+			t.Errorf("expect events in order, got: %v at %d", e["message"], i)
+		}
+	}
+}
+
+func TestWriter_Resend(t *testing.T) {
+	srv, err := lumberjacktest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.PartialAcks(true)
+
+	w, err := Dial(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	srv.FailAfter(3)
+	if err := w.WriteRecords(records(5)); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	events := srv.Events()
+	if len(events) != 5 {
+		t.Fatalf("expect 5 events without duplicates of acknowledged ones, got: %d", len(events))
+	}
+	if events[3]["message"] != "message 3" {
+		t.Errorf("expect not acknowledged events sent again, got: %v", events[3]["message"])
+	}
+}
+
+func TestWriter_TLS(t *testing.T) {
+	ca, err := tlstest.NewCA("test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ca.Issue("server", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlstest.ServerConfig(cert, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := lumberjacktest.Serve(ln)
+	defer srv.Close()
+
+	w, err := Dial(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{TLSConfig: &tls.Config{RootCAs: ca.Pool()}})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Info("over tls"); err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if n := len(srv.Events()); n != 1 {
+		t.Errorf("expect 1 event, got: %d", n)
+	}
+}
@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"log/syslog"
+	"sync"
+
+	"slogger/syslog/format"
+)
+
+// RecordWriter - SyslogWriter which also stores records sent with WriteRecord
+type RecordWriter struct {
+	SyslogWriter
+
+	muRecords sync.RWMutex
+	records   []format.Record
+}
+
+// WriteRecord - stores copy of record
+func (w *RecordWriter) WriteRecord(r *format.Record) error {
+	w.muRecords.Lock()
+	defer w.muRecords.Unlock()
+
+	w.records = append(w.records, *r)
+	return nil
+}
+
+// Records - return copy of stored records
+func (w *RecordWriter) Records() []format.Record {
+	w.muRecords.RLock()
+	defer w.muRecords.RUnlock()
+
+	return append([]format.Record(nil), w.records...)
+}
+
+// RecordsCount - return count of stored records of certain level
+func (w *RecordWriter) RecordsCount(l syslog.Priority) int {
+	w.muRecords.RLock()
+	defer w.muRecords.RUnlock()
+
+	cnt := 0
+	for _, r := range w.records {
+		if r.Priority&0x07 == l&0x07 {
+			cnt++
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,99 @@
+package syslog
+
+import (
+	"strings"
+
+	"slogger/syslog/format"
+)
+
+// MultilinePolicy - how messages with line breaks (stack traces, SQL) are sent
+type MultilinePolicy int
+
+const (
+	// MultilineEscape - escape line breaks and other control characters (C0, DEL) rsyslog style
+	// ("#012" for LF, "#015" for CR, "#033" for ESC)
+	MultilineEscape MultilinePolicy = iota
+	// MultilineKeep - send line breaks as is. TCP and TLS use octet-counting framing. Local unix stream
+	// socket (rsyslog imuxsock, journald) does not support it, line breaks are escaped there
+	MultilineKeep
+	// MultilineSplit - send every line as separate record "[<group> <line>/<lines>] <line>",
+	// other control characters are escaped
+	MultilineSplit
+)
+
+// Fields set on records of split multi-line message
+const (
+	FieldLineGroup = "line_group"
+	FieldLine      = "line"
+	FieldLines     = "lines"
+)
+
+// controlEscaper - escapes control characters, invalid UTF-8 is left for formatter
+var controlEscaper = format.Sanitize{InvalidUTF8: format.InvalidUTF8Keep}
+
+// MultilineWriter - optional interface of SyslogWriter. Writers of structured formats (GELF, journald,
+// Loki, OTLP...) carry line breaks in message field themselves and return true, multi-line policy
+// is not applied to their records
+type MultilineWriter interface {
+	Multiline() bool
+}
+
+// WithMultiline - set multi-line messages policy of syslog framed transports, MultilineEscape by default
+func WithMultiline(p MultilinePolicy) Option {
+	return func(s *syslog) {
+		s.multiline = p
+	}
+}
+
+// framing - returns framing of tcp and tls transports for multi-line policy p
+func framing(p MultilinePolicy) Framing {
+	if p == MultilineKeep {
+		return FramingOctetCounting
+	}
+	return FramingNonTransparent
+}
+
+// multilinePolicy - returns multi-line policy of records sent with protocol. Unix stream socket has
+// no octet-counting framing, so line breaks are escaped instead of kept
+func (s *syslog) multilinePolicy(protocol string) MultilinePolicy {
+	if s.multiline == MultilineKeep && ProtocolNetwork(protocol) == SyslogProtocolUnix {
+		return MultilineEscape
+	}
+	return s.multiline
+}
+
+// multilineRecords - returns records to send to w of protocol instead of r according to multi-line policy
+func (s *syslog) multilineRecords(w SyslogWriter, protocol string, r *bufferRecord) []*bufferRecord {
+	if mw, ok := w.(MultilineWriter); ok && mw.Multiline() {
+		return []*bufferRecord{r}
+	}
+	v := strings.TrimSuffix(r.value, "\n")
+	policy := s.multilinePolicy(protocol)
+	if policy == MultilineKeep {
+		return []*bufferRecord{r}
+	}
+	if policy == MultilineEscape || !strings.ContainsAny(v, "\r\n") {
+		e := controlEscaper.Message(v)
+		if e == v {
+			return []*bufferRecord{r}
+		}
+		c := *r
+		c.value = e
+		return []*bufferRecord{&c}
+	}
+
+	lines := strings.Split(strings.Replace(v, "\r\n", "\n", -1), "\n")
+	id := splitID()
+	recs := make([]*bufferRecord, 0, len(lines))
+	for i, l := range lines {
+		c := *r
+		c.value = splitPrefix(id, i+1, len(lines)) + controlEscaper.Message(strings.TrimSuffix(l, "\r"))
+		c.ctx = ContextWithFields(r.ctx, format.Fields{
+			FieldLineGroup: id,
+			FieldLine:      i + 1,
+			FieldLines:     len(lines),
+		})
+		recs = append(recs, &c)
+	}
+	return recs
+}
@@ -0,0 +1,242 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	slog "log/syslog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/mock"
+	"slogger/syslog/relp/relptest"
+)
+
+// testServer - local syslog server storing received messages
+type testServer struct {
+	addr  string
+	close func()
+
+	mu       sync.Mutex
+	messages []string
+	// received - returns received messages if server stores them itself
+	received func() []string
+}
+
+func (s *testServer) add(m string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, m)
+}
+
+// waitMessages - waits up to 1 second for n messages and returns received ones
+func (s *testServer) waitMessages(n int) []string {
+	var res []string
+	for i := 0; i < 100; i++ {
+		if s.received != nil {
+			res = s.received()
+		} else {
+			s.mu.Lock()
+			res = append([]string(nil), s.messages...)
+			s.mu.Unlock()
+		}
+		if len(res) >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return res
+}
+
+// newTestServer - starts tcp, udp or relp server. tcp server reads frames with framing f
+func newTestServer(t *testing.T, protocol string, f Framing) *testServer {
+	s := &testServer{}
+	switch protocol {
+	case SyslogProtocolRELP:
+		rs, err := relptest.NewServer()
+		if err != nil {
+			t.Fatalf("cannot start relp server: %v", err)
+		}
+		s.addr = rs.Addr
+		s.received = rs.Messages
+		s.close = func() { rs.Close() }
+	case SyslogProtocolUDP:
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("cannot listen: %v", err)
+		}
+		s.addr = pc.LocalAddr().String()
+		s.close = func() { pc.Close() }
+		go func() {
+			b := make([]byte, 65536)
+			for {
+				n, _, err := pc.ReadFrom(b)
+				if err != nil {
+					return
+				}
+				s.add(string(b[:n]))
+			}
+		}()
+	default:
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("cannot listen: %v", err)
+		}
+		s.addr = ln.Addr().String()
+		s.close = func() { ln.Close() }
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go readFrames(conn, f, s.add)
+			}
+		}()
+	}
+	return s
+}
+
+// readFrames - reads stream transport frames from conn
+func readFrames(conn net.Conn, f Framing, add func(string)) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		if f == FramingNonTransparent {
+			m, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			add(strings.TrimSuffix(m, "\n"))
+			continue
+		}
+
+		l, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(l, " "))
+		if err != nil {
+			return
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return
+		}
+		add(string(b))
+	}
+}
+
+func TestSyslog_multiline(t *testing.T) {
+	m := "first\nsecond\r\n\tthird\x1b\x00\n"
+	for _, protocol := range []string{SyslogProtocolTCP, SyslogProtocolUDP, SyslogProtocolRELP} {
+		for _, policy := range []MultilinePolicy{MultilineEscape, MultilineKeep, MultilineSplit} {
+			srv := newTestServer(t, protocol, framing(policy))
+
+			sender, err := New(context.Background(), protocol, srv.addr, "tag", 8, 10*time.Millisecond, 8,
+				WithFormatter(format.Raw{}), WithMultiline(policy))
+			if err != nil {
+				t.Fatalf("cannot create syslog sender: %v", err)
+			}
+			sender.Send(context.Background(), slog.LOG_ERR, m)
+			sender.Send(context.Background(), slog.LOG_ERR, "single")
+			sender.Close()
+
+			var expect []string
+			switch policy {
+			case MultilineEscape:
+				expect = []string{"first#012second#015#012#011third#033#000", "single"}
+			case MultilineKeep:
+				expect = []string{"first\nsecond\r\n\tthird\x1b\x00", "single"}
+			case MultilineSplit:
+				expect = []string{"first", "second", "#011third#033#000", "single"}
+			}
+
+			got := srv.waitMessages(len(expect))
+			srv.close()
+			if len(got) != len(expect) {
+				t.Errorf("%s, policy %d: expect %d messages, got %d: %q", protocol, policy, len(expect), len(got), got)
+				continue
+			}
+			for i, e := range expect {
+				g := got[i]
+				if policy == MultilineSplit && i < 3 {
+					prefix := g[:strings.Index(g, "] ")+2]
+					if !strings.HasSuffix(prefix, " "+strconv.Itoa(i+1)+"/3] ") {
+						t.Errorf("%s, policy %d: unexpected line prefix %q", protocol, policy, prefix)
+					}
+					g = strings.TrimPrefix(g, prefix)
+				}
+				if g != e {
+					t.Errorf("%s, policy %d: message %d: expect %q, got %q", protocol, policy, i, e, g)
+				}
+			}
+		}
+	}
+}
+
+func TestSyslog_multilineFields(t *testing.T) {
+	s := &syslog{multiline: MultilineSplit}
+	recs := s.multilineRecords(&mock.SyslogWriter{}, SyslogProtocolTCP, &bufferRecord{ctx: context.Background(), value: "a\nb"})
+	if len(recs) != 2 {
+		t.Fatalf("expect 2 records, got: %d", len(recs))
+	}
+	f0, f1 := FieldsFromContext(recs[0].ctx), FieldsFromContext(recs[1].ctx)
+	if f0[FieldLineGroup] == nil || f0[FieldLineGroup] != f1[FieldLineGroup] {
+		t.Errorf("expect common line group, got %v and %v", f0[FieldLineGroup], f1[FieldLineGroup])
+	}
+	if f0[FieldLine] != 1 || f1[FieldLine] != 2 || f1[FieldLines] != 2 {
+		t.Errorf("unexpected line fields: %v, %v", f0, f1)
+	}
+}
+
+func TestSyslog_multilineGELF(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	msgs := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		m, err := bufio.NewReader(conn).ReadString(0)
+		if err != nil {
+			return
+		}
+		msgs <- strings.TrimSuffix(m, "\x00")
+	}()
+
+	sender, err := New(context.Background(), SyslogProtocolGELFTCP, ln.Addr().String(), "tag", 8,
+		10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	defer sender.Close()
+
+	m := "first\nsecond"
+	sender.Send(context.Background(), slog.LOG_ERR, m)
+	select {
+	case p := <-msgs:
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(p), &obj); err != nil {
+			t.Fatalf("expect valid JSON, got %q: %v", p, err)
+		}
+		if obj["full_message"] != m {
+			t.Errorf("expect full_message %q, got: %v", m, obj["full_message"])
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message not received")
+	}
+}
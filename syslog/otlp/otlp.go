@@ -0,0 +1,408 @@
+package otlp
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/httppush"
+)
+
+// LogsPath - path of OTLP/HTTP logs endpoint, used if URL has no path
+const LogsPath = "/v1/logs"
+
+// ScopeName - instrumentation scope of exported records
+const ScopeName = "slogger"
+
+// Resource and record attributes set by writer (OpenTelemetry semantic conventions)
+const (
+	AttrServiceName = "service.name"
+	AttrHostName    = "host.name"
+	AttrProcessPID  = "process.pid"
+	// AttrSyslogProcID - non-numeric record ProcID (e.g. container ID), process.pid is int
+	AttrSyslogProcID = "syslog.procid"
+	AttrCodeFilepath = "code.filepath"
+	AttrCodeLineno   = "code.lineno"
+)
+
+const severityMask = 0x07
+
+// severityNumbers - OpenTelemetry SeverityNumber of syslog severities
+var severityNumbers = [...]int{
+	21, // emerg - FATAL
+	19, // alert - ERROR3
+	18, // crit - ERROR2
+	17, // err - ERROR
+	13, // warning - WARN
+	10, // notice - INFO2
+	9,  // info - INFO
+	5,  // debug - DEBUG
+}
+
+// SeverityNumber - returns OpenTelemetry SeverityNumber of priority severity
+func SeverityNumber(p syslog.Priority) int {
+	return severityNumbers[p&severityMask]
+}
+
+// Config - OTLP/HTTP exporter settings
+type Config struct {
+	// Headers - additional request headers (e.g. authorization)
+	Headers map[string]string
+	// ResourceAttributes - additional resource attributes (e.g. "deployment.environment")
+	ResourceAttributes map[string]string
+	// Gzip - compress request body
+	Gzip bool
+	// Retry - retries of 429, 502, 503, 504 responses and network errors
+	Retry httppush.Retry
+	// Client - HTTP client, httppush.DefaultClient if nil
+	Client *http.Client
+}
+
+// Writer - OTLP/HTTP JSON logs exporter. Batch of records is exported with one request, records are
+// grouped into resources by tag (service.name), hostname and process. It implements syslog.SyslogWriter,
+// syslog.RecordWriter and syslog.BatchWriter, so can be used as sender sink
+type Writer struct {
+	priority syslog.Priority
+	tag      string
+	hostname string
+	url      string
+	cfg      Config
+	ctx      context.Context
+}
+
+// New - creates exporter to collector at rawURL (e.g. "http://otel-collector:4318")
+func New(rawURL string, priority syslog.Priority, tag string, cfg Config) (*Writer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("otlp: unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = LogsPath
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	if cfg.Retry.Retryable == nil {
+		cfg.Retry.Retryable = retryable
+	}
+	hostname, _ := os.Hostname()
+
+	return &Writer{
+		priority: priority,
+		tag:      tag,
+		hostname: hostname,
+		url:      u.String(),
+		cfg:      cfg,
+		ctx:      context.Background(),
+	}, nil
+}
+
+// retryable - statuses to be retried by OTLP/HTTP specification
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// SetContext - set context which interrupts waiting between export retries when done (e.g. when
+// sender is closed), context.Background() by default
+func (w *Writer) SetContext(ctx context.Context) {
+	w.ctx = ctx
+}
+
+// Close - does nothing, requests are not kept between batches
+func (w *Writer) Close() error {
+	return nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.WriteRecord(&format.Record{Priority: w.priority, Message: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *Writer) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (w *Writer) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (w *Writer) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (w *Writer) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (w *Writer) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (w *Writer) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (w *Writer) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (w *Writer) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
+
+// Multiline - log record bodies keep line breaks of multi-line messages, so they are sent as is
+func (w *Writer) Multiline() bool {
+	return true
+}
+
+// WriteRecord - exports one record
+func (w *Writer) WriteRecord(r *format.Record) error {
+	return w.WriteRecords([]*format.Record{r})
+}
+
+// WriteRecords - exports records with one request
+func (w *Writer) WriteRecords(recs []*format.Record) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(w.encode(recs, time.Now()))
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if w.cfg.Gzip {
+		if body, err = httppush.Gzip(body); err != nil {
+			return err
+		}
+		header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range w.cfg.Headers {
+		header.Set(k, v)
+	}
+	return httppush.Post(w.ctx, httppush.Request{Client: w.cfg.Client, URL: w.url, Header: header, Body: body}, w.cfg.Retry)
+}
+
+// exportRequest - ExportLogsServiceRequest in OTLP/JSON encoding
+// (int64 as strings, trace and span IDs as hex, enums as numbers)
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(n int64) anyValue {
+	s := strconv.FormatInt(n, 10)
+	return anyValue{IntValue: &s}
+}
+
+// value - returns AnyValue of field value, unknown types are sent as strings
+func value(v interface{}) anyValue {
+	switch t := v.(type) {
+	case string:
+		return stringValue(t)
+	case bool:
+		return anyValue{BoolValue: &t}
+	case int:
+		return intValue(int64(t))
+	case int8:
+		return intValue(int64(t))
+	case int16:
+		return intValue(int64(t))
+	case int32:
+		return intValue(int64(t))
+	case int64:
+		return intValue(t)
+	case uint8:
+		return intValue(int64(t))
+	case uint16:
+		return intValue(int64(t))
+	case uint32:
+		return intValue(int64(t))
+	case float32:
+		return doubleValue(float64(t))
+	case float64:
+		return doubleValue(t)
+	}
+	return stringValue(fmt.Sprint(v))
+}
+
+// doubleValue - returns AnyValue of f, NaN and infinities (not representable in JSON) are sent as strings
+func doubleValue(f float64) anyValue {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return stringValue(fmt.Sprint(f))
+	}
+	return anyValue{DoubleValue: &f}
+}
+
+// hexID - returns id if it is hex of n bytes and not all zeros, empty string otherwise
+func hexID(id string, n int) string {
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != n {
+		return ""
+	}
+	for _, c := range b {
+		if c != 0 {
+			return strings.ToLower(id)
+		}
+	}
+	return ""
+}
+
+// encode - builds export request, records are grouped into resources in order of appearance
+func (w *Writer) encode(recs []*format.Record, observed time.Time) exportRequest {
+	var (
+		req   exportRequest
+		index = map[string]int{}
+	)
+	for _, r := range recs {
+		attrs := w.resourceAttributes(r)
+		k := resourceKey(attrs)
+		i, ok := index[k]
+		if !ok {
+			i = len(req.ResourceLogs)
+			index[k] = i
+			req.ResourceLogs = append(req.ResourceLogs, resourceLogs{
+				Resource:  resource{Attributes: attrs},
+				ScopeLogs: []scopeLogs{{Scope: scope{Name: ScopeName}}},
+			})
+		}
+		sl := &req.ResourceLogs[i].ScopeLogs[0]
+		sl.LogRecords = append(sl.LogRecords, w.logRecord(r, observed))
+	}
+	return req
+}
+
+// resourceAttributes - returns sorted resource attributes of record
+func (w *Writer) resourceAttributes(r *format.Record) []keyValue {
+	tag, hostname := r.Tag, r.Hostname
+	if tag == "" {
+		tag = w.tag
+	}
+	if hostname == "" {
+		hostname = w.hostname
+	}
+	attrs := []keyValue{
+		{Key: AttrServiceName, Value: stringValue(tag)},
+		{Key: AttrHostName, Value: stringValue(hostname)},
+	}
+	pid := r.PID
+	if pid == 0 {
+		pid = os.Getpid()
+	}
+	if r.ProcID != "" {
+		if n, err := strconv.Atoi(r.ProcID); err == nil {
+			pid = n
+		} else {
+			attrs = append(attrs, keyValue{Key: AttrSyslogProcID, Value: stringValue(r.ProcID)})
+		}
+	}
+	attrs = append(attrs, keyValue{Key: AttrProcessPID, Value: intValue(int64(pid))})
+	for k, v := range w.cfg.ResourceAttributes {
+		attrs = append(attrs, keyValue{Key: k, Value: stringValue(v)})
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+	return attrs
+}
+
+// resourceKey - returns string identifying resource attributes
+func resourceKey(attrs []keyValue) string {
+	b, _ := json.Marshal(attrs)
+	return string(b)
+}
+
+func (w *Writer) logRecord(r *format.Record, observed time.Time) logRecord {
+	ts := r.Timestamp
+	if ts.IsZero() {
+		ts = observed
+	}
+	lr := logRecord{
+		TimeUnixNano:         strconv.FormatInt(ts.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       SeverityNumber(r.Priority),
+		SeverityText:         strings.ToUpper(format.SeverityName(r.Priority)),
+		Body:                 stringValue(strings.TrimSuffix(r.Message, "\n")),
+		TraceID:              hexID(r.TraceID, 16),
+		SpanID:               hexID(r.SpanID, 8),
+	}
+
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lr.Attributes = append(lr.Attributes, keyValue{Key: k, Value: value(r.Fields[k])})
+	}
+	if i := strings.LastIndexByte(r.Caller, ':'); i > 0 {
+		line, _ := strconv.ParseInt(r.Caller[i+1:], 10, 64)
+		lr.Attributes = append(lr.Attributes,
+			keyValue{Key: AttrCodeFilepath, Value: stringValue(r.Caller[:i])},
+			keyValue{Key: AttrCodeLineno, Value: intValue(line)})
+	}
+	return lr
+}
@@ -0,0 +1,142 @@
+package otlp
+
+import (
+	"encoding/json"
+	"log/syslog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/httppush"
+)
+
+func TestWriter_WriteRecords(t *testing.T) {
+	reqs := make(chan exportRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != LogsPath || r.Header.Get("Authorization") != "Bearer token" ||
+			r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot decode request: %v", err)
+		}
+		reqs <- req
+	}))
+	defer srv.Close()
+
+	w, err := New(srv.URL, syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatalf("cannot create writer: %v", err)
+	}
+	ts := time.Unix(1562769255, 5)
+	err = w.WriteRecords([]*format.Record{
+		{Priority: syslog.LOG_ERR, Timestamp: ts, Hostname: "host", PID: 42, Message: "failed\n", Caller: "main.go:7",
+			Fields:  format.Fields{"user": "john", "attempt": 3, "ok": false, "ratio": 0.5},
+			TraceID: "4BF92F3577B34DA6A3CE929D0E0E4736", SpanID: "00f067aa0ba902b7"},
+		{Priority: syslog.LOG_INFO, Timestamp: ts, Hostname: "host", PID: 42, Message: "done", TraceID: "bad"},
+		{Priority: syslog.LOG_DEBUG, Timestamp: ts, Hostname: "host", PID: 42, Tag: "worker", Message: "debug"},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+
+	req := <-reqs
+	if len(req.ResourceLogs) != 2 {
+		t.Fatalf("expect 2 resources, got: %d", len(req.ResourceLogs))
+	}
+	res := req.ResourceLogs[0]
+	b, _ := json.Marshal(res.Resource.Attributes)
+	expect := `[{"key":"host.name","value":{"stringValue":"host"}},{"key":"process.pid","value":{"intValue":"42"}},` +
+		`{"key":"service.name","value":{"stringValue":"app"}}]`
+	if string(b) != expect {
+		t.Errorf("expect resource attributes %s, got: %s", expect, b)
+	}
+
+	recs := res.ScopeLogs[0].LogRecords
+	if len(recs) != 2 || res.ScopeLogs[0].Scope.Name != ScopeName {
+		t.Fatalf("expect 2 records of scope %s, got: %+v", ScopeName, res.ScopeLogs)
+	}
+	r := recs[0]
+	if r.TimeUnixNano != "1562769255000000005" || r.SeverityNumber != 17 || r.SeverityText != "ERR" ||
+		*r.Body.StringValue != "failed" || r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	b, _ = json.Marshal(r.Attributes)
+	expect = `[{"key":"attempt","value":{"intValue":"3"}},{"key":"ok","value":{"boolValue":false}},` +
+		`{"key":"ratio","value":{"doubleValue":0.5}},{"key":"user","value":{"stringValue":"john"}},` +
+		`{"key":"code.filepath","value":{"stringValue":"main.go"}},{"key":"code.lineno","value":{"intValue":"7"}}]`
+	if string(b) != expect {
+		t.Errorf("expect attributes %s, got: %s", expect, b)
+	}
+	if recs[1].TraceID != "" || recs[1].SeverityNumber != 9 {
+		t.Errorf("unexpected record: %+v", recs[1])
+	}
+	if tag := *req.ResourceLogs[1].Resource.Attributes[2].Value.StringValue; tag != "worker" {
+		t.Errorf("expect service.name worker, got: %s", tag)
+	}
+}
+
+func TestWriter_encodeValues(t *testing.T) {
+	w, err := New("http://localhost:4318", syslog.LOG_WARNING|syslog.LOG_DAEMON, "app", Config{})
+	if err != nil {
+		t.Fatalf("cannot create writer: %v", err)
+	}
+	req := w.encode([]*format.Record{
+		{Priority: syslog.LOG_ERR, Hostname: "host", ProcID: "77", Message: "m",
+			Fields: format.Fields{"nan": math.NaN(), "inf": math.Inf(-1)}},
+		{Priority: syslog.LOG_ERR, Hostname: "host", PID: 42, ProcID: "c0ffee", Message: "m"},
+	}, time.Now())
+	if _, err := json.Marshal(req); err != nil {
+		t.Fatalf("expect non-finite floats encoded, got: %v", err)
+	}
+
+	b, _ := json.Marshal(req.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Attributes)
+	expect := `[{"key":"inf","value":{"stringValue":"-Inf"}},{"key":"nan","value":{"stringValue":"NaN"}}]`
+	if string(b) != expect {
+		t.Errorf("expect attributes %s, got: %s", expect, b)
+	}
+	b, _ = json.Marshal(req.ResourceLogs[0].Resource.Attributes)
+	expect = `[{"key":"host.name","value":{"stringValue":"host"}},{"key":"process.pid","value":{"intValue":"77"}},` +
+		`{"key":"service.name","value":{"stringValue":"app"}}]`
+	if string(b) != expect {
+		t.Errorf("expect numeric ProcID as process.pid %s, got: %s", expect, b)
+	}
+	b, _ = json.Marshal(req.ResourceLogs[1].Resource.Attributes)
+	expect = `[{"key":"host.name","value":{"stringValue":"host"}},{"key":"process.pid","value":{"intValue":"42"}},` +
+		`{"key":"service.name","value":{"stringValue":"app"}},{"key":"syslog.procid","value":{"stringValue":"c0ffee"}}]`
+	if string(b) != expect {
+		t.Errorf("expect non-numeric ProcID as %s %s, got: %s", AttrSyslogProcID, expect, b)
+	}
+}
+
+func TestWriter_Retry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := New(srv.URL, syslog.LOG_DAEMON, "app", Config{Retry: httppush.Retry{MinBackoff: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("cannot create writer: %v", err)
+	}
+	// 503 is retried, 500 is not
+	if err := w.Err("message"); err == nil {
+		t.Errorf("expect error of 500 response, got no error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expect 2 requests, got: %d", n)
+	}
+}
@@ -2,6 +2,7 @@ package relp
 
 import (
 	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +13,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"slogger/syslog/conncheck"
+	"slogger/syslog/format"
 )
 
 // Client - A client to a RELP server
@@ -22,6 +26,10 @@ type Client struct {
 	raddr    string
 	timeout  time.Duration
 
+	formatter format.Formatter
+	// tlsConfig - if set, connection (and every reconnection) is made over TLS
+	tlsConfig *tls.Config
+
 	mu         sync.Mutex
 	connection net.Conn
 
@@ -33,31 +41,48 @@ const (
 	relpSoftware = "slogger"
 	severityMask = 0x07
 	facilityMask = 0xf8
+
+	// DefaultTLSMinVersion - minimum TLS version of DialTLS if config has no MinVersion
+	DefaultTLSMinVersion = tls.VersionTLS12
 )
 
+// errNotConnected - connection was closed or could not be made
+var errNotConnected = errors.New("relp: not connected")
+
 // Dial like dial in log/syslog
 func Dial(raddr string, priority syslog.Priority, tag string, timeout time.Duration) (*Client, error) {
-	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
-		return nil, errors.New("log/syslog: invalid priority")
-	}
+	return dial(raddr, priority, tag, timeout, nil)
+}
 
-	if tag == "" {
-		tag = os.Args[0]
+// DialTLS - like Dial, but connects over TLS (rsyslog imrelp with tls="on").
+// Client certificates, CA pool and server name are taken from cfg. If cfg.MinVersion
+// is not set, DefaultTLSMinVersion is used
+func DialTLS(raddr string, priority syslog.Priority, tag string, timeout time.Duration, cfg *tls.Config) (*Client, error) {
+	return dial(raddr, priority, tag, timeout, clientTLSConfig(cfg))
+}
+
+// clientTLSConfig - returns copy of cfg with DefaultTLSMinVersion if MinVersion is not set
+func clientTLSConfig(cfg *tls.Config) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
 	}
-	hostname, _ := os.Hostname()
+	cfg = cfg.Clone()
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = DefaultTLSMinVersion
+	}
+	return cfg
+}
 
-	c := &Client{
-		priority: priority,
-		tag:      tag,
-		hostname: hostname,
-		raddr:    raddr,
-		timeout:  timeout,
+func dial(raddr string, priority syslog.Priority, tag string, timeout time.Duration, tlsConfig *tls.Config) (*Client, error) {
+	c, err := newClient(raddr, priority, tag, timeout, tlsConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	err := c.connect()
+	err = c.connect()
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +90,28 @@ func Dial(raddr string, priority syslog.Priority, tag string, timeout time.Durat
 
 }
 
+// newClient - creates not connected client, it connects on first write
+func newClient(raddr string, priority syslog.Priority, tag string, timeout time.Duration, tlsConfig *tls.Config) (*Client, error) {
+	if priority < 0 || priority > syslog.LOG_LOCAL7|syslog.LOG_DEBUG {
+		return nil, errors.New("log/syslog: invalid priority")
+	}
+
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	hostname, _ := os.Hostname()
+
+	return &Client{
+		priority:  priority,
+		tag:       tag,
+		hostname:  hostname,
+		raddr:     raddr,
+		timeout:   timeout,
+		formatter: format.Default,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
 // connect makes a connection to the rsyslog server.
 // It must be called with w.mu held.
 func (c *Client) connect() (err error) {
@@ -73,8 +120,13 @@ func (c *Client) connect() (err error) {
 		c.connection = nil
 	}
 
-	c.connection, err = net.DialTimeout("tcp", c.raddr, c.timeout)
+	if c.tlsConfig != nil {
+		c.connection, err = tls.DialWithDialer(&net.Dialer{Timeout: c.timeout}, "tcp", c.raddr, c.tlsConfig)
+	} else {
+		c.connection, err = net.DialTimeout("tcp", c.raddr, c.timeout)
+	}
 	if err != nil {
+		c.connection = nil
 		return err
 	}
 
@@ -91,6 +143,8 @@ func (c *Client) connect() (err error) {
 
 	offerResponse, err := readMessage(c.connection)
 	if err != nil {
+		c.connection.Close()
+		c.connection = nil
 		return err
 	}
 
@@ -138,13 +192,54 @@ func (c *Client) sendMessage(msg Message) (err error) {
 	return err
 }
 
+// SetFormatter - set formatter used to build syslog message from record
+func (c *Client) SetFormatter(f format.Formatter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f == nil {
+		f = format.Default
+	}
+	c.formatter = f
+}
+
 // SetDeadline - make the next operation timeout if not completed before the given time
 func (c *Client) SetDeadline(t time.Time) error {
 	return c.connection.SetDeadline(t)
 }
 
+// CheckConn - checks that connection kept between batches is not broken (e.g. closed by server)
+func (c *Client) CheckConn() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connection == nil {
+		return errNotConnected
+	}
+	return conncheck.ReadProbe(c.connection)
+}
+
+// isConnected - returns false if connection could not be made
+func (c *Client) isConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.connection != nil
+}
+
+// reconnect - makes new connection
+func (c *Client) reconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.connect()
+}
+
 // Close - Closes the connection gracefully
 func (c *Client) Close() (err error) {
+	if c.connection == nil {
+		return nil
+	}
 	closeMessage := Message{
 		Txn:     c.nextTxn,
 		Command: CommandClose,
@@ -160,84 +255,46 @@ func (c *Client) Close() (err error) {
 	return nil
 }
 
-func (c Client) Write(b []byte) (int, error) {
-	return c.swrite(b, c.tag)
-}
-
-func (c Client) Emerg(m string) error {
-	return c.semerg(m, c.tag)
-}
-
-func (c Client) Alert(m string) error {
-	return c.salert(m, c.tag)
-}
-
-func (c Client) Crit(m string) error {
-	return c.scrit(m, c.tag)
-}
-
-func (c Client) Err(m string) error {
-	return c.serr(m, c.tag)
-}
-
-func (c Client) Warning(m string) error {
-	return c.swarning(m, c.tag)
-}
-
-func (c Client) Notice(m string) error {
-	return c.snotice(m, c.tag)
+func (c *Client) Write(b []byte) (int, error) {
+	return c.writeAndRetry(&format.Record{Priority: c.priority, Message: string(b)})
 }
 
-func (c Client) Info(m string) error {
-	return c.sinfo(m, c.tag)
+func (c *Client) Emerg(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
 }
 
-func (c Client) Debug(m string) error {
-	return c.sdebug(m, c.tag)
+func (c *Client) Alert(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
 }
 
-// Internal client funcs
-func (c Client) swrite(b []byte, tag string) (int, error) {
-	return c.writeAndRetry(c.priority, tag, string(b))
+func (c *Client) Crit(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
 }
 
-func (c Client) semerg(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_EMERG, tag, m)
-	return err
+func (c *Client) Err(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
 }
 
-func (c Client) salert(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_ALERT, tag, m)
-	return err
+func (c *Client) Warning(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
 }
 
-func (c Client) scrit(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_CRIT, tag, m)
-	return err
+func (c *Client) Notice(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
 }
 
-func (c Client) serr(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_ERR, tag, m)
-	return err
-}
-
-func (c Client) swarning(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_WARNING, tag, m)
-	return err
-}
-
-func (c Client) snotice(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_NOTICE, tag, m)
-	return err
+func (c *Client) Info(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
 }
 
-func (c Client) sinfo(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_INFO, tag, m)
-	return err
+func (c *Client) Debug(m string) error {
+	return c.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
 }
 
-func (c Client) sdebug(m, tag string) error {
-	_, err := c.writeAndRetry(syslog.LOG_DEBUG, tag, m)
+// WriteRecord - sends record with its fields and caller. Priority facility, hostname
+// and tag of record are set by client
+func (c *Client) WriteRecord(r *format.Record) error {
+	_, err := c.writeAndRetry(r)
 	return err
 }
 
@@ -280,38 +337,40 @@ func readMessage(conn io.Reader) (message Message, err error) {
 	return message, err
 }
 
-func (c *Client) writeAndRetry(p syslog.Priority, tag, s string) (int, error) {
-	pr := (c.priority & facilityMask) | (p & severityMask)
-
+func (c *Client) writeAndRetry(r *format.Record) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	rec := *r
+	rec.Priority = (c.priority & facilityMask) | (r.Priority & severityMask)
+	if rec.Tag == "" {
+		rec.Tag = c.tag
+	}
+	if rec.PID == 0 {
+		rec.PID = os.Getpid()
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+
 	if c.connection != nil {
-		if n, err := c.write(pr, tag, s); err == nil {
+		if n, err := c.write(&rec); err == nil {
 			return n, err
 		}
 	}
 	if err := c.connect(); err != nil {
 		return 0, err
 	}
-	return c.write(pr, tag, s)
+	return c.write(&rec)
 }
 
-func (c *Client) write(p syslog.Priority, tag, msg string) (int, error) {
-	m := syslogMessage(p, c.hostname, tag, msg)
+func (c *Client) write(r *format.Record) (int, error) {
+	if r.Hostname == "" {
+		r.Hostname = c.hostname
+	}
+	m := c.formatter.Format(r)
 	if err := c.sendString(m); err != nil {
 		return 0, err
 	}
-	return len(msg), nil
-}
-
-func syslogMessage(p syslog.Priority, hostname, tag, msg string) string {
-	if strings.HasSuffix(msg, "\n") {
-		msg = strings.TrimSuffix(msg, "\n")
-	}
-
-	ts := time.Now().Format(time.RFC3339)
-	return fmt.Sprintf("<%d>%s %s %s[%d]: %s",
-		p, ts, hostname,
-		tag, os.Getpid(), msg)
+	return len(r.Message), nil
 }
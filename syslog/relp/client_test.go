@@ -0,0 +1,116 @@
+package relp
+
+import (
+	"crypto/tls"
+	"log/syslog"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/relp/relptest"
+	"slogger/syslog/tlsconfig/tlstest"
+)
+
+func newTLSServer(t *testing.T, serverCfg *tls.Config) *relptest.Server {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	return relptest.Serve(ln)
+}
+
+func testPKI(t *testing.T) (*tlstest.CA, *tlstest.Certificate, *tlstest.Certificate) {
+	ca, err := tlstest.NewCA("test CA")
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	server, err := ca.Issue("server", false)
+	if err != nil {
+		t.Fatalf("cannot issue server certificate: %v", err)
+	}
+	client, err := ca.Issue("client", true)
+	if err != nil {
+		t.Fatalf("cannot issue client certificate: %v", err)
+	}
+	return ca, server, client
+}
+
+func TestDial(t *testing.T) {
+	srv, err := relptest.NewServer()
+	if err != nil {
+		t.Fatalf("cannot start server: %v", err)
+	}
+	defer srv.Close()
+
+	c, err := Dial(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", time.Second)
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	c.SetFormatter(format.Raw{})
+	if err := c.Err("Test message"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	c.Close()
+
+	if msgs := srv.Messages(); len(msgs) != 1 || msgs[0] != "Test message" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
+
+func TestDialTLS(t *testing.T) {
+	ca, server, client := testPKI(t)
+	srv := newTLSServer(t, tlstest.ServerConfig(server, ca))
+	defer srv.Close()
+
+	c, err := DialTLS(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", time.Second, &tls.Config{
+		RootCAs:      ca.Pool(),
+		Certificates: []tls.Certificate{client.TLS},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	defer c.Close()
+	c.SetFormatter(format.Raw{})
+
+	if err := c.Err("first"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+
+	// reconnect through TLS after connection is lost
+	srv.CloseClientConnections()
+	if err := c.Err("second"); err != nil {
+		t.Errorf("expect no error after reconnect, got: %v", err)
+	}
+
+	if msgs := srv.Messages(); strings.Join(msgs, ",") != "first,second" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+	if n := srv.Sessions(); n != 2 {
+		t.Errorf("expect 2 sessions, got: %d", n)
+	}
+}
+
+func TestDialTLS_MinVersion(t *testing.T) {
+	ca, server, _ := testPKI(t)
+	serverCfg := tlstest.ServerConfig(server, nil)
+	serverCfg.MinVersion = tls.VersionTLS11
+	serverCfg.MaxVersion = tls.VersionTLS11
+	srv := newTLSServer(t, serverCfg)
+	defer srv.Close()
+
+	if _, err := DialTLS(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", time.Second,
+		&tls.Config{RootCAs: ca.Pool()}); err == nil {
+		t.Errorf("expect error for TLS 1.1 server with default minimum version, got no error")
+	}
+}
+
+func TestDialTLS_Untrusted(t *testing.T) {
+	_, server, _ := testPKI(t)
+	srv := newTLSServer(t, tlstest.ServerConfig(server, nil))
+	defer srv.Close()
+
+	if _, err := DialTLS(srv.Addr, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag", time.Second, nil); err == nil {
+		t.Errorf("expect error for untrusted server certificate, got no error")
+	}
+}
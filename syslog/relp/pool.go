@@ -0,0 +1,388 @@
+package relp
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/syslog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+// Balance - how pool chooses connection for message or batch
+type Balance int
+
+const (
+	// BalanceRoundRobin - connections are used in turn (default)
+	BalanceRoundRobin Balance = iota
+	// BalanceLeastOutstanding - connection with least messages waiting for rsp is used
+	BalanceLeastOutstanding
+)
+
+// DefaultConnsPerAddr - connections per address of pool
+const DefaultConnsPerAddr = 1
+
+// Defaults of quarantine of connections which cannot connect to collector
+const (
+	DefaultQuarantine    = time.Second
+	DefaultMaxQuarantine = 30 * time.Second
+)
+
+// errQuarantined - no connection of pool can be used until quarantine of failed connections expires
+var errQuarantined = errors.New("relp: all pool connections are quarantined after connect failures")
+
+// PoolConfig - RELP connection pool settings
+type PoolConfig struct {
+	// Addrs - collector addresses
+	Addrs []string
+	// ConnsPerAddr - connections to every address, DefaultConnsPerAddr if 0
+	ConnsPerAddr int
+	Balance      Balance
+	Timeout      time.Duration
+	// TLSConfig - if set, connections are made over TLS (see DialTLS)
+	TLSConfig *tls.Config
+	// Quarantine - how long connection which failed to connect is not used, DefaultQuarantine if 0.
+	// It doubles on every next failed connect up to MaxQuarantine (DefaultMaxQuarantine if 0)
+	Quarantine    time.Duration
+	MaxQuarantine time.Duration
+}
+
+// quarantine - connect failures backoff of pool connection
+type quarantine struct {
+	mu      sync.Mutex
+	until   time.Time
+	backoff time.Duration
+}
+
+// Pool - RELP connections to several collectors. Every connection has its own reconnect handling
+// and keeps order of messages sent to it; batches are split between connections and sent
+// concurrently, so throughput is not limited by waiting for rsp of a single connection.
+// It implements syslog.SyslogWriter and syslog.RecordWriter, so can be used as sender sink
+type Pool struct {
+	priority syslog.Priority
+	balance  Balance
+	clients  []*Client
+	// outstanding - messages reserved for client and waiting for rsp, accessed atomically
+	outstanding []int64
+	// sending - held while part of batch is sent over client, so parts are not interleaved
+	sending []sync.Mutex
+	// quarantine - connections which failed to connect are skipped until their backoff expires
+	quarantine    []quarantine
+	minQuarantine time.Duration
+	maxQuarantine time.Duration
+	next          uint32
+}
+
+// DialPool - connects to collectors of cfg. Unreachable collectors are reconnected on write,
+// error is returned only if no collector is reachable
+func DialPool(cfg PoolConfig, priority syslog.Priority, tag string) (*Pool, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("relp: no pool addresses")
+	}
+	if cfg.ConnsPerAddr <= 0 {
+		cfg.ConnsPerAddr = DefaultConnsPerAddr
+	}
+	if cfg.Quarantine <= 0 {
+		cfg.Quarantine = DefaultQuarantine
+	}
+	if cfg.MaxQuarantine <= 0 {
+		cfg.MaxQuarantine = DefaultMaxQuarantine
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLSConfig != nil {
+		tlsConfig = clientTLSConfig(cfg.TLSConfig)
+	}
+
+	n := cfg.ConnsPerAddr * len(cfg.Addrs)
+	p := &Pool{
+		priority:      priority,
+		balance:       cfg.Balance,
+		outstanding:   make([]int64, n),
+		sending:       make([]sync.Mutex, n),
+		quarantine:    make([]quarantine, n),
+		minQuarantine: cfg.Quarantine,
+		maxQuarantine: cfg.MaxQuarantine,
+	}
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		lastErr   error
+		reachable int
+	)
+	for i := 0; i < cfg.ConnsPerAddr; i++ {
+		for _, addr := range cfg.Addrs {
+			c, err := newClient(addr, priority, tag, cfg.Timeout, tlsConfig)
+			if err != nil {
+				return nil, err
+			}
+			p.clients = append(p.clients, c)
+
+			wg.Add(1)
+			go func(i int, c *Client) {
+				defer wg.Done()
+				c.mu.Lock()
+				err := c.connect()
+				c.mu.Unlock()
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					p.connectFailed(i)
+					lastErr = err
+					return
+				}
+				reachable++
+			}(len(p.clients)-1, c)
+		}
+	}
+	wg.Wait()
+
+	if reachable == 0 {
+		p.Close()
+		return nil, lastErr
+	}
+	return p, nil
+}
+
+// Len - returns count of connections
+func (p *Pool) Len() int {
+	return len(p.clients)
+}
+
+// SetFormatter - set formatter of all connections
+func (p *Pool) SetFormatter(f format.Formatter) {
+	for _, c := range p.clients {
+		c.SetFormatter(f)
+	}
+}
+
+// Close - closes all connections
+func (p *Pool) Close() error {
+	var err error
+	for _, c := range p.clients {
+		c.mu.Lock()
+		if cErr := c.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// pick - returns index of connection for next message or part of batch and reserves it for n records,
+// so next picks see them as outstanding. Quarantined connections are skipped while others are available
+func (p *Pool) pick(n int) int {
+	next := atomic.AddUint32(&p.next, 1) - 1
+	start := int(next % uint32(len(p.clients)))
+	best, min := start, int64(-1)
+	// scan from round robin position, so ties are spread too
+	for k := 0; k < len(p.clients); k++ {
+		i := (start + k) % len(p.clients)
+		if !p.available(i) {
+			continue
+		}
+		o := atomic.LoadInt64(&p.outstanding[i])
+		if min < 0 || o < min {
+			best, min = i, o
+		}
+		if p.balance != BalanceLeastOutstanding {
+			break
+		}
+	}
+	atomic.AddInt64(&p.outstanding[best], int64(n))
+	return best
+}
+
+// available - returns false while connection i is quarantined
+func (p *Pool) available(i int) bool {
+	q := &p.quarantine[i]
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return !time.Now().Before(q.until)
+}
+
+// connectFailed - quarantines connection i, every next failure doubles quarantine up to maximum
+func (p *Pool) connectFailed(i int) {
+	q := &p.quarantine[i]
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.backoff *= 2
+	if q.backoff == 0 {
+		q.backoff = p.minQuarantine
+	}
+	if q.backoff > p.maxQuarantine {
+		q.backoff = p.maxQuarantine
+	}
+	q.until = time.Now().Add(q.backoff)
+}
+
+// connected - resets quarantine backoff of connection i
+func (p *Pool) connected(i int) {
+	q := &p.quarantine[i]
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.backoff = 0
+	q.until = time.Time{}
+}
+
+// CheckConn - checks connections kept between batches concurrently and reconnects broken ones,
+// quarantined connections are not reconnected. Returns error only if no collector is reachable
+func (p *Pool) CheckConn() error {
+	errs := make([]error, len(p.clients))
+	var wg sync.WaitGroup
+	for i, c := range p.clients {
+		if !p.available(i) {
+			errs[i] = errQuarantined
+			continue
+		}
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			if errs[i] = c.CheckConn(); errs[i] != nil {
+				if errs[i] = c.reconnect(); errs[i] != nil {
+					p.connectFailed(i)
+				} else {
+					p.connected(i)
+				}
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+// send - sends records in order over connection i reserved by pick. If connection fails, the rest of
+// records is sent as a whole over next available connection, not interleaved with other parts sent over it.
+// Quarantined connections are skipped, so collectors which are down do not cost dial timeout on every send
+func (p *Pool) send(i int, recs []*format.Record) error {
+	err := errQuarantined
+	for k := 0; k < len(p.clients); k++ {
+		j := (i + k) % len(p.clients)
+		if !p.available(j) {
+			if k == 0 {
+				atomic.AddInt64(&p.outstanding[j], -int64(len(recs)))
+			}
+			continue
+		}
+		if k > 0 {
+			atomic.AddInt64(&p.outstanding[j], int64(len(recs)))
+		}
+		if recs, err = p.sendOver(j, recs); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// sendOver - sends records over connection i and releases their reservation, returns records which
+// were not sent because connection failed
+func (p *Pool) sendOver(i int, recs []*format.Record) ([]*format.Record, error) {
+	p.sending[i].Lock()
+	defer p.sending[i].Unlock()
+	defer atomic.AddInt64(&p.outstanding[i], -int64(len(recs)))
+
+	c := p.clients[i]
+	for len(recs) > 0 {
+		if err := c.WriteRecord(recs[0]); err != nil {
+			if !c.isConnected() {
+				p.connectFailed(i)
+			}
+			return recs, err
+		}
+		recs = recs[1:]
+	}
+	p.connected(i)
+	return nil, nil
+}
+
+// WriteRecords - splits records into contiguous parts, one per connection, and sends parts
+// concurrently. Order of records is kept within every part, also when part fails over to other connection
+func (p *Pool) WriteRecords(recs []*format.Record) error {
+	parts := len(p.clients)
+	if parts > len(recs) {
+		parts = len(recs)
+	}
+	if parts <= 1 {
+		if len(recs) == 0 {
+			return nil
+		}
+		return p.send(p.pick(len(recs)), recs)
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		err error
+	)
+	size := (len(recs) + parts - 1) / parts
+	for start := 0; start < len(recs); start += size {
+		end := start + size
+		if end > len(recs) {
+			end = len(recs)
+		}
+		i := p.pick(end - start)
+		wg.Add(1)
+		go func(i int, part []*format.Record) {
+			defer wg.Done()
+			if sErr := p.send(i, part); sErr != nil {
+				mu.Lock()
+				err = sErr
+				mu.Unlock()
+			}
+		}(i, recs[start:end])
+	}
+	wg.Wait()
+	return err
+}
+
+// WriteRecord - sends record over connection chosen by balance
+func (p *Pool) WriteRecord(r *format.Record) error {
+	return p.send(p.pick(1), []*format.Record{r})
+}
+
+func (p *Pool) Write(b []byte) (int, error) {
+	if err := p.WriteRecord(&format.Record{Priority: p.priority, Message: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (p *Pool) Emerg(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_EMERG, Message: m})
+}
+
+func (p *Pool) Alert(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_ALERT, Message: m})
+}
+
+func (p *Pool) Crit(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_CRIT, Message: m})
+}
+
+func (p *Pool) Err(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_ERR, Message: m})
+}
+
+func (p *Pool) Warning(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_WARNING, Message: m})
+}
+
+func (p *Pool) Notice(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_NOTICE, Message: m})
+}
+
+func (p *Pool) Info(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_INFO, Message: m})
+}
+
+func (p *Pool) Debug(m string) error {
+	return p.WriteRecord(&format.Record{Priority: syslog.LOG_DEBUG, Message: m})
+}
@@ -0,0 +1,309 @@
+package relp
+
+import (
+	"fmt"
+	"log/syslog"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/relp/relptest"
+)
+
+func newTestServers(t *testing.T, n int) []*relptest.Server {
+	var srvs []*relptest.Server
+	for i := 0; i < n; i++ {
+		srv, err := relptest.NewServer()
+		if err != nil {
+			t.Fatalf("cannot start server: %v", err)
+		}
+		srvs = append(srvs, srv)
+	}
+	return srvs
+}
+
+func testRecords(n int) []*format.Record {
+	recs := make([]*format.Record, n)
+	for i := range recs {
+		recs[i] = &format.Record{Priority: syslog.LOG_ERR, Message: strconv.Itoa(i)}
+	}
+	return recs
+}
+
+// checkOrder - checks that messages of every server are in ascending order and total count is n
+func checkOrder(t *testing.T, srvs []*relptest.Server, n int) {
+	total := 0
+	for i, srv := range srvs {
+		prev := -1
+		for _, m := range srv.Messages() {
+			k, _ := strconv.Atoi(m)
+			if k <= prev {
+				t.Errorf("server %d: unexpected order %d after %d", i, k, prev)
+			}
+			prev = k
+			total++
+		}
+	}
+	if total != n {
+		t.Errorf("expect %d messages, got: %d", n, total)
+	}
+}
+
+func TestPool_WriteRecords(t *testing.T) {
+	for _, balance := range []Balance{BalanceRoundRobin, BalanceLeastOutstanding} {
+		t.Run(fmt.Sprint(balance), func(t *testing.T) {
+			srvs := newTestServers(t, 2)
+			p, err := DialPool(PoolConfig{
+				Addrs:   []string{srvs[0].Addr, srvs[1].Addr},
+				Balance: balance,
+				Timeout: time.Second,
+			}, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+			if err != nil {
+				t.Fatalf("cannot dial pool: %v", err)
+			}
+			p.SetFormatter(format.Raw{})
+			if p.Len() != 2 {
+				t.Errorf("expect 2 connections, got: %d", p.Len())
+			}
+
+			if err := p.WriteRecords(testRecords(20)); err != nil {
+				t.Errorf("expect no error, got: %v", err)
+			}
+			p.Close()
+			for _, srv := range srvs {
+				srv.Close()
+				if len(srv.Messages()) == 0 {
+					t.Errorf("expect batch spread across pool, server %s got no messages", srv.Addr)
+				}
+			}
+			checkOrder(t, srvs, 20)
+		})
+	}
+}
+
+func TestPool_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+
+	srvs := newTestServers(t, 1)
+	defer srvs[0].Close()
+	p, err := DialPool(PoolConfig{
+		Addrs:        []string{down, srvs[0].Addr},
+		ConnsPerAddr: 2,
+		Timeout:      time.Second,
+	}, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial pool: %v", err)
+	}
+	p.SetFormatter(format.Raw{})
+
+	if err := p.WriteRecords(testRecords(10)); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Err("single"); err != nil {
+			t.Errorf("expect no error, got: %v", err)
+		}
+	}
+	p.Close()
+
+	if n := len(srvs[0].Messages()); n != 13 {
+		t.Errorf("expect 13 messages, got: %d", n)
+	}
+
+	if _, err := DialPool(PoolConfig{Addrs: []string{down}, Timeout: time.Second}, syslog.LOG_DAEMON, "tag"); err == nil {
+		t.Errorf("expect error if no collector is reachable, got no error")
+	}
+}
+
+func TestPool_CheckConn(t *testing.T) {
+	srvs := newTestServers(t, 1)
+	defer srvs[0].Close()
+	p, err := DialPool(PoolConfig{Addrs: []string{srvs[0].Addr}, ConnsPerAddr: 2, Timeout: time.Second},
+		syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial pool: %v", err)
+	}
+	defer p.Close()
+
+	if err := p.CheckConn(); err != nil {
+		t.Errorf("expect alive connections, got: %v", err)
+	}
+	if n := srvs[0].Sessions(); n != 2 {
+		t.Errorf("expect 2 sessions, got: %d", n)
+	}
+
+	srvs[0].CloseClientConnections()
+	time.Sleep(10 * time.Millisecond)
+	if err := p.CheckConn(); err != nil {
+		t.Errorf("expect broken connections reconnected, got: %v", err)
+	}
+	if n := srvs[0].Sessions(); n != 4 {
+		t.Errorf("expect 4 sessions after reconnect, got: %d", n)
+	}
+}
+
+func TestPool_pick(t *testing.T) {
+	p := &Pool{
+		balance:     BalanceLeastOutstanding,
+		clients:     make([]*Client, 3),
+		outstanding: make([]int64, 3),
+		quarantine:  make([]quarantine, 3),
+	}
+	// parts are reserved when picked, so parts of one batch go to different connections
+	for i, expect := range []int{0, 1, 2, 1} {
+		n := 1
+		if i == 0 {
+			n = 10
+		}
+		if c := p.pick(n); c != expect {
+			t.Errorf("pick %d: expect connection %d, got: %d", i, expect, c)
+		}
+	}
+	if p.outstanding[0] != 10 || p.outstanding[1] != 2 || p.outstanding[2] != 1 {
+		t.Errorf("unexpected outstanding records: %v", p.outstanding)
+	}
+}
+
+func TestPool_FailoverOrder(t *testing.T) {
+	srvs := newTestServers(t, 2)
+	defer srvs[1].Close()
+	p, err := DialPool(PoolConfig{Addrs: []string{srvs[0].Addr, srvs[1].Addr}, Timeout: time.Second},
+		syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial pool: %v", err)
+	}
+	p.SetFormatter(format.Raw{})
+	srvs[0].Close()
+
+	// part of failed connection is sent after or before part of the other one, not interleaved
+	if err := p.WriteRecords(testRecords(200)); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	p.Close()
+
+	msgs := srvs[1].Messages()
+	if len(msgs) != 200 {
+		t.Fatalf("expect 200 messages, got: %d", len(msgs))
+	}
+	runs := 1
+	for i := 1; i < len(msgs); i++ {
+		prev, _ := strconv.Atoi(msgs[i-1])
+		k, _ := strconv.Atoi(msgs[i])
+		if k != prev+1 {
+			runs++
+		}
+	}
+	if runs != 2 {
+		t.Errorf("expect 2 contiguous parts, got %d runs: %v", runs, msgs)
+	}
+	for _, st := range p.outstanding {
+		if st != 0 {
+			t.Errorf("expect no outstanding records, got: %v", p.outstanding)
+		}
+	}
+}
+
+// refusingListener - accepts connections and closes them at once, so RELP session cannot be opened
+func refusingListener(t *testing.T) (string, *int32) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var accepted int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String(), &accepted
+}
+
+func TestPool_Quarantine(t *testing.T) {
+	down, accepted := refusingListener(t)
+	srvs := newTestServers(t, 1)
+	defer srvs[0].Close()
+	p, err := DialPool(PoolConfig{
+		Addrs:      []string{down, srvs[0].Addr},
+		Timeout:    time.Second,
+		Quarantine: 50 * time.Millisecond,
+	}, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial pool: %v", err)
+	}
+	defer p.Close()
+	p.SetFormatter(format.Raw{})
+
+	for i := 0; i < 5; i++ {
+		if err := p.WriteRecords(testRecords(4)); err != nil {
+			t.Errorf("expect no error, got: %v", err)
+		}
+	}
+	if err := p.CheckConn(); err != nil {
+		t.Errorf("expect healthy connection, got: %v", err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Errorf("expect quarantined collector not dialed again, got %d dials", n)
+	}
+	if n := len(srvs[0].Messages()); n != 20 {
+		t.Errorf("expect 20 messages sent over healthy connection, got: %d", n)
+	}
+
+	// after quarantine collector is dialed again, failure doubles quarantine
+	time.Sleep(60 * time.Millisecond)
+	if err := p.WriteRecords(testRecords(2)); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 2 {
+		t.Errorf("expect collector dialed after quarantine, got %d dials", n)
+	}
+	if b := p.quarantine[0].backoff; b != 100*time.Millisecond {
+		t.Errorf("expect quarantine doubled, got: %s", b)
+	}
+	if n := len(srvs[0].Messages()); n != 22 {
+		t.Errorf("expect failed part resent over healthy connection, got %d messages", n)
+	}
+}
+
+func TestPool_AllQuarantined(t *testing.T) {
+	down, accepted := refusingListener(t)
+	srvs := newTestServers(t, 1)
+	p, err := DialPool(PoolConfig{
+		Addrs:      []string{down, srvs[0].Addr},
+		Timeout:    time.Second,
+		Quarantine: time.Hour,
+	}, syslog.LOG_WARNING|syslog.LOG_DAEMON, "tag")
+	if err != nil {
+		t.Fatalf("cannot dial pool: %v", err)
+	}
+	defer p.Close()
+	srvs[0].Close()
+
+	if err := p.Err("lost"); err == nil {
+		t.Errorf("expect error when all collectors are down, got no error")
+	}
+	if err := p.Err("lost"); err != errQuarantined {
+		t.Errorf("expect quarantine error without dialing, got: %v", err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Errorf("expect quarantined collector not dialed again, got %d dials", n)
+	}
+	for _, o := range p.outstanding {
+		if o != 0 {
+			t.Errorf("expect no outstanding records, got: %v", p.outstanding)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package relptest provides in-process RELP server for tests
+package relptest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const openResponse = "200 OK\nrelp_version=0\nrelp_software=relptest\ncommands=syslog"
+
+// Server - RELP server which acknowledges and stores all syslog messages
+type Server struct {
+	Addr string
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []string
+	conns    map[net.Conn]struct{}
+	opened   int
+	closed   bool
+}
+
+// NewServer - starts server on random local port
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return Serve(ln), nil
+}
+
+// Serve - starts server on listener ln (e.g. TLS listener)
+func Serve(ln net.Listener) *Server {
+	s := &Server{
+		Addr:  ln.Addr().String(),
+		ln:    ln,
+		conns: make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// Messages - returns data of received syslog commands
+func (s *Server) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.messages...)
+}
+
+// Sessions - returns count of accepted RELP sessions ("open" commands)
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.opened
+}
+
+// CloseClientConnections - drops all client connections without RELP "serverclose"
+func (s *Server) CloseClientConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close - stops server and closes all connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.ln.Close()
+	s.CloseClientConnections()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	r := bufio.NewReader(c)
+	for {
+		txn, cmd, data, err := readFrame(r)
+		if err != nil {
+			return
+		}
+
+		rsp := "200 OK"
+		switch cmd {
+		case "open":
+			rsp = openResponse
+			s.mu.Lock()
+			s.opened++
+			s.mu.Unlock()
+		case "syslog":
+			s.mu.Lock()
+			s.messages = append(s.messages, data)
+			s.mu.Unlock()
+		case "close":
+			fmt.Fprintf(c, "%d rsp 0\n", txn)
+			return
+		}
+		if _, err := fmt.Fprintf(c, "%d rsp %d %s\n", txn, len(rsp), rsp); err != nil {
+			return
+		}
+	}
+}
+
+// readFrame - reads "TXNR SP COMMAND SP DATALEN [SP DATA] TRAILER" frame
+func readFrame(r *bufio.Reader) (txn int, cmd, data string, err error) {
+	t, err := r.ReadString(' ')
+	if err != nil {
+		return 0, "", "", err
+	}
+	if txn, err = strconv.Atoi(strings.TrimSpace(t)); err != nil {
+		return 0, "", "", err
+	}
+	if cmd, err = r.ReadString(' '); err != nil {
+		return 0, "", "", err
+	}
+	cmd = strings.TrimSpace(cmd)
+
+	var l []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, "", "", err
+		}
+		if b == ' ' || b == '\n' {
+			if b == '\n' {
+				r.UnreadByte()
+			}
+			break
+		}
+		l = append(l, b)
+	}
+	dataLen, err := strconv.Atoi(string(l))
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	d := make([]byte, dataLen)
+	if _, err = io.ReadFull(r, d); err != nil {
+		return 0, "", "", err
+	}
+	if _, err = r.ReadString('\n'); err != nil {
+		return 0, "", "", err
+	}
+	return txn, cmd, string(d), nil
+}
@@ -2,14 +2,31 @@ package syslog
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	slog "log/syslog"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"slogger/syslog/alert"
+	"slogger/syslog/console"
+	slFile "slogger/syslog/file"
+	"slogger/syslog/format"
+	"slogger/syslog/forward"
+	"slogger/syslog/gelf"
+	"slogger/syslog/identity"
+	"slogger/syslog/loki"
+	"slogger/syslog/lumberjack"
+	"slogger/syslog/otlp"
 	slRelp "slogger/syslog/relp"
 )
 
@@ -17,11 +34,91 @@ const (
 	SyslogProtocolTCP  = "tcp"
 	SyslogProtocolUDP  = "udp"
 	SyslogProtocolRELP = "relp"
+	SyslogProtocolTLS  = "tls"
+
+	// SyslogProtocolUnix, SyslogProtocolUnixgram - local syslog socket. If address is empty,
+	// /dev/log, /var/run/syslog or /var/run/log is used
+	SyslogProtocolUnix     = "unix"
+	SyslogProtocolUnixgram = "unixgram"
+
+	SyslogProtocolRELPTLS = "relp+tls"
+
+	SyslogProtocolGELFUDP = "gelf+udp"
+	SyslogProtocolGELFTCP = "gelf+tcp"
+
+	// SyslogProtocolJournald - systemd-journald native protocol. If address is empty,
+	// journald.DefaultSocket is used
+	SyslogProtocolJournald = "journald"
+
+	// SyslogProtocolConsole - human-friendly output for local development, address is
+	// "stdout" or "stderr" (default). Output settings are set with WithConsole
+	SyslogProtocolConsole = "console"
+
+	// SyslogProtocolLoki - Grafana Loki push API, address is URL (e.g. "http://loki:3100").
+	// Labels, compression and retries are set with WithLokiConfig
+	SyslogProtocolLoki = "loki"
+
+	// SyslogProtocolOTLP - OpenTelemetry OTLP/HTTP JSON logs, address is collector URL
+	// (e.g. "http://otel-collector:4318"). Headers and retries are set with WithOTLPConfig
+	SyslogProtocolOTLP = "otlp"
+
+	// SyslogProtocolForward, SyslogProtocolForwardUnix - Fluentd Forward protocol (Fluentd, Fluent Bit)
+	// over tcp or unix socket. Acks and shared key handshake are set with WithForwardConfig
+	SyslogProtocolForward     = "forward"
+	SyslogProtocolForwardUnix = "forward+unix"
+
+	// SyslogProtocolLumberjack, SyslogProtocolLumberjackTLS - Lumberjack v2 (Logstash beats input),
+	// over tcp or TLS (WithTLSConfig). Windows and compression are set with WithLumberjackConfig
+	SyslogProtocolLumberjack    = "lumberjack"
+	SyslogProtocolLumberjackTLS = "lumberjack+tls"
+
+	// SyslogProtocolFile - local file, address is file path. Rotation is set with WithFileConfig
+	SyslogProtocolFile = "file"
 )
 
+// ProtocolNetwork - returns network ("tcp", "udp", "unix"...) used by protocol, protocol itself if its
+// dialer does not implement NetworkDialer
+func ProtocolNetwork(syslogProtocol string) string {
+	if d, ok := lookupTransport(syslogProtocol).(NetworkDialer); ok && d.Network() != "" {
+		return d.Network()
+	}
+	return syslogProtocol
+}
+
+// urlHostPort - returns "host:port" of http(s) URL
+func urlHostPort(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443"), nil
+	}
+	return net.JoinHostPort(u.Hostname(), "80"), nil
+}
+
+// Probe - checks that syslog server is reachable with protocol (see Prober). "srv://" address is resolved
+// with net.DefaultResolver and is reachable if any of its targets is
+func Probe(syslogProtocol, syslogAddr string) error {
+	if isSRV(syslogAddr) {
+		targets, err := resolveSRV(context.Background(), nil, syslogAddr)
+		if err != nil {
+			return err
+		}
+		return probeTargets(syslogProtocol, targets)
+	}
+	return probeTransport(lookupTransport(syslogProtocol), syslogProtocol, syslogAddr)
+}
+
 type Sender interface {
 	io.Closer
 	Send(ctx context.Context, level slog.Priority, v string) error
+	Stats() Stats
+	// Probe - checks that syslog server of sender is reachable (see Probe)
+	Probe() error
 }
 
 type SyslogWriter interface {
@@ -37,35 +134,275 @@ type SyslogWriter interface {
 	Debug(string) error
 }
 
-type dialMethodFunc func(context.Context, string, string, string) (slog SyslogWriter, ok bool)
+// RecordWriter - optional interface of SyslogWriter which can send record with
+// its structured data (fields, caller). Writer sets facility of record, and hostname, tag
+// and PID if they are not set.
+type RecordWriter interface {
+	WriteRecord(r *format.Record) error
+}
+
+// BatchWriter - optional interface of SyslogWriter which sends whole batch of records at once
+// (e.g. concurrently over several connections)
+type BatchWriter interface {
+	WriteRecords(recs []*format.Record) error
+}
+
+// DialMethodFunc - opens writer of protocol, address and tag, ok is false if it failed.
+// Set with SetDialMethod it replaces all transports of sender (e.g. in tests), see also RegisterTransport
+type DialMethodFunc func(ctx context.Context, protocol, addr, tag string) (slog SyslogWriter, ok bool)
+
+// Option - optional sender setting, passed to New
+type Option func(s *syslog)
+
+// WithFormatter - set formatter shared by tcp, udp and relp writers
+func WithFormatter(f format.Formatter) Option {
+	return func(s *syslog) {
+		s.formatter = f
+	}
+}
+
+// WithCaller - collect "file:line" of Send caller. skip is the number of stack frames
+// to ascend above the direct caller of Send (0 - caller of Send itself)
+func WithCaller(skip int) Option {
+	return func(s *syslog) {
+		s.caller = true
+		s.callerSkip = skip
+	}
+}
+
+// WithGELFCompression - set compression of gelf+udp messages
+func WithGELFCompression(c gelf.Compression) Option {
+	return func(s *syslog) {
+		s.gelfCompression = c
+	}
+}
+
+// WithTimestampPrecision - truncate event time to multiple of p (e.g. time.Millisecond)
+func WithTimestampPrecision(p time.Duration) Option {
+	return func(s *syslog) {
+		s.tsPrecision = p
+	}
+}
+
+// WithTimestampLocation - set time zone of event time (e.g. time.UTC), time.Local by default
+func WithTimestampLocation(loc *time.Location) Option {
+	return func(s *syslog) {
+		s.tsLocation = loc
+	}
+}
+
+// WithSendTime - add time of sending record to syslog as field with given name
+func WithSendTime(field string) Option {
+	return func(s *syslog) {
+		s.sendTimeField = field
+	}
+}
+
+// WithIdentity - set provider of HOSTNAME, APP-NAME, PROCID and host metadata fields.
+// Provider is called once by New
+func WithIdentity(p identity.Provider) Option {
+	return func(s *syslog) {
+		s.identityProvider = p
+	}
+}
+
+// WithHeaderValidation - make New return error if tag or hostname of identity has characters not
+// allowed in syslog header or is too long (see format.ValidateTag). Not validated by default, use
+// format.Sanitize to clean them instead
+func WithHeaderValidation() Option {
+	return func(s *syslog) {
+		s.validateHeaders = true
+	}
+}
+
+// WithTLSConfig - set TLS config of tls and relp+tls protocols (CA pool, client certificate, server name).
+// Use tlsconfig.Pin to pin server certificates and tlsconfig.Reloader to reload certificates from files
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *syslog) {
+		s.tlsConfig = cfg
+	}
+}
+
+// WithFileConfig - set rotation, retention and fsync settings of file protocol
+func WithFileConfig(cfg slFile.Config) Option {
+	return func(s *syslog) {
+		s.fileConfig = cfg
+	}
+}
+
+// WithRELPPool - send relp and relp+tls batches over pool of connections (see relp.Pool).
+// If cfg.Addrs is empty, address passed to New is used; TLS config of relp+tls is set with WithTLSConfig
+func WithRELPPool(cfg slRelp.PoolConfig) Option {
+	return func(s *syslog) {
+		s.relpPool = &cfg
+	}
+}
+
+// WithLokiConfig - set labels, compression and retry settings of loki protocol
+func WithLokiConfig(cfg loki.Config) Option {
+	return func(s *syslog) {
+		s.lokiConfig = cfg
+	}
+}
+
+// WithOTLPConfig - set headers, resource attributes and retry settings of otlp protocol
+func WithOTLPConfig(cfg otlp.Config) Option {
+	return func(s *syslog) {
+		s.otlpConfig = cfg
+	}
+}
+
+// WithForwardConfig - set tag, acks and shared key handshake of forward protocols
+func WithForwardConfig(cfg forward.Config) Option {
+	return func(s *syslog) {
+		s.forwardConfig = cfg
+	}
+}
+
+// WithLumberjackConfig - set window size, compression and timeout of lumberjack protocols
+func WithLumberjackConfig(cfg lumberjack.Config) Option {
+	return func(s *syslog) {
+		s.lumberjackConfig = cfg
+	}
+}
+
+// WithIdleTimeout - close connection kept between batches after d without messages, DefaultIdleTimeout by default.
+// Zero or negative d - connection is made for every batch and closed after it
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *syslog) {
+		s.idleTimeout = d
+	}
+}
+
+// WithAlerts - notify about high severity (crit and higher by default) messages with webhook or mail,
+// in addition to sending them to syslog. Alerts are raised on Send, independently of syslog connection
+func WithAlerts(cfg alert.Config) Option {
+	return func(s *syslog) {
+		s.alertConfig = &cfg
+	}
+}
+
+// WithConsole - send to console instead of syslog server (whatever protocol is passed to New),
+// for local development
+func WithConsole(cfg console.Config) Option {
+	return func(s *syslog) {
+		s.syslogProtocol = SyslogProtocolConsole
+		s.consoleConfig = cfg
+	}
+}
 
 type syslog struct {
+	// stats is accessed atomically, keep it first for 64-bit alignment
+	stats Stats
+
 	syslogProtocol, syslogAddr, syslogTag string
-	dialMethod                            dialMethodFunc
+	hostname                              string
+	dialMethod                            DialMethodFunc
+	formatter                             format.Formatter
+	caller                                bool
+	callerSkip                            int
+	gelfCompression                       gelf.Compression
+	maxMessageSize                        int
+	maxMessageSizeSet                     bool
+	sizePolicy                            SizePolicy
+	tsPrecision                           time.Duration
+	tsLocation                            *time.Location
+	sendTimeField                         string
+	identityProvider                      identity.Provider
+	identity                              identity.Identity
+	validateHeaders                       bool
+	multiline                             MultilinePolicy
+	tlsConfig                             *tls.Config
+	fileConfig                            slFile.Config
+	consoleConfig                         console.Config
+	relpPool                              *slRelp.PoolConfig
+	lokiConfig                            loki.Config
+	otlpConfig                            otlp.Config
+	forwardConfig                         forward.Config
+	lumberjackConfig                      lumberjack.Config
+	endpoints                             []Endpoint
+	failoverConfig                        FailoverConfig
+	failover                              *failover
+	srv                                   srvCache
+	traceExtractor                        TraceExtractor
+	alertConfig                           *alert.Config
+	idleTimeout                           time.Duration
+	alerter                               *alert.Alerter
 
 	bufferSendPeriod time.Duration
 	bufferSendCount  int
 	syslogBuffer     *messageBuffer
-	cancelFunc       context.CancelFunc
-	wgSyslogSend     sync.WaitGroup
+	// conn - writer kept between batches, used by send goroutine only
+	conn         SyslogWriter
+	connEndpoint Endpoint
+	connIndex    int
+	connTargets  []string
+	connUsed     time.Time
+	cancelFunc   context.CancelFunc
+	wgSyslogSend sync.WaitGroup
 }
 
 func New(ctx context.Context, syslogProtocol, syslogAddr, syslogTag string,
-	bufferSizeMessages int, bufferSendPeriod time.Duration, bufferSendCount int) (Sender, error) {
+	bufferSizeMessages int, bufferSendPeriod time.Duration, bufferSendCount int, opts ...Option) (Sender, error) {
 	// Init sender
 	sender := &syslog{
 		syslogProtocol: syslogProtocol,
 		syslogAddr:     syslogAddr,
 		syslogTag:      syslogTag,
 		syslogBuffer:   newMessageBuffer(bufferSizeMessages),
-		dialMethod:     syslogDial,
+		formatter:      format.Default,
+		tsLocation:     time.Local,
+		idleTimeout:    DefaultIdleTimeout,
+	}
+	sender.dialMethod = sender.syslogDial
+	sender.hostname, _ = os.Hostname()
+	for _, opt := range opts {
+		opt(sender)
 	}
+	if sender.syslogProtocol != "" {
+		if err := checkProtocol(sender.syslogProtocol); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range sender.endpoints {
+		if e.Protocol != "" {
+			if err := checkProtocol(e.Protocol); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if sender.validateHeaders {
+		if err := format.ValidateTag(syslogTag); err != nil {
+			return nil, err
+		}
+	}
+	if sender.identityProvider != nil {
+		id, err := sender.identityProvider.Identity()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get host identity: %v", err)
+		}
+		if sender.validateHeaders {
+			if err := format.ValidateHostname(id.Hostname); err != nil {
+				return nil, err
+			}
+		}
+		sender.identity = id
+	}
+	if sender.alertConfig != nil {
+		sender.alerter = alert.New(*sender.alertConfig)
+	}
+	sender.failover = newFailover(Endpoint{Protocol: sender.syslogProtocol, Addr: sender.syslogAddr},
+		sender.endpoints, sender.failoverConfig)
 
 	// Start sender goroutine
 	cancelCtx, cancelFunc := context.WithCancel(ctx)
 	sender.cancelFunc = cancelFunc
 	sender.wgSyslogSend.Add(1)
 	go sender.syslogSend(cancelCtx, bufferSendPeriod, bufferSendCount)
+	if len(sender.failover.endpoints) > 1 {
+		sender.wgSyslogSend.Add(1)
+		go sender.healthCheck(cancelCtx)
+	}
 
 	return sender, nil
 }
@@ -75,6 +412,9 @@ func (s *syslog) Close() error {
 		return nil
 	}
 	s.cancelFunc()
+	if s.alerter != nil {
+		defer s.alerter.Close()
+	}
 
 	c := make(chan struct{})
 	go func() {
@@ -89,51 +429,103 @@ func (s *syslog) Close() error {
 	}
 }
 
+// Probe - checks that syslog server is reachable with protocol and address of sender,
+// or any of failover endpoints
+func (s *syslog) Probe() error {
+	var err error
+	for _, e := range s.failover.endpoints {
+		if err = s.probeAddr(e.Protocol, e.Addr); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
 // Send - adds message (v inteface{}) to send buffer with level
 func (s *syslog) Send(ctx context.Context, level slog.Priority, v string) error {
 	if s.syslogBuffer == nil {
 		return fmt.Errorf("message buffer not inited")
 	}
-	if err := s.syslogBuffer.add(&bufferRecord{
+	r := &bufferRecord{
 		ctx:   ctx,
-		ts:    time.Now().UTC().Format(time.RFC3339Nano),
+		ts:    time.Now(),
 		level: level,
 		value: v,
-	}); err != nil {
+	}
+	if s.caller {
+		if _, file, line, ok := runtime.Caller(s.callerSkip + 1); ok {
+			r.caller = file + ":" + strconv.Itoa(line)
+		}
+	}
+	if s.alerter != nil && s.alerter.Enabled(level) {
+		s.alert(r)
+	}
+	if err := s.syslogBuffer.add(r); err != nil {
 		return fmt.Errorf("cannot add message to syslog buffer: %v", err)
 	}
 
 	return nil
 }
 
-func (s *syslog) SetDialMethod(dialFunc dialMethodFunc) {
+// alert - passes record to alerter with tag and hostname filled as writers do
+func (s *syslog) alert(r *bufferRecord) {
+	rec := s.formatRecord(r)
+	if rec.Tag == "" {
+		rec.Tag = s.syslogTag
+	}
+	if rec.Hostname == "" {
+		rec.Hostname = s.hostname
+	}
+	if err := s.alerter.WriteRecord(rec); err != nil {
+		log.Printf("cannot raise alert: %v", err)
+	}
+}
+
+// SetDialMethod - replaces dialing of writers
+func (s *syslog) SetDialMethod(dialFunc DialMethodFunc) {
 	s.dialMethod = dialFunc
 }
 
 func (s *syslog) syslogSend(ctx context.Context, bufferSendPeriod time.Duration, maxRecsToSend int) {
 	defer s.wgSyslogSend.Done()
 
-	recs := make([]*bufferRecord, maxRecsToSend, maxRecsToSend)
+	var (
+		// pending - records no endpoint accepted, they are retried before new ones
+		pending []*bufferRecord
+		retryAt time.Time
+		backoff time.Duration
+	)
 	tickCh := time.Tick(bufferSendPeriod)
 
 loop:
 	for {
 		select {
 		case <-tickCh:
-			i := 0
-			for !s.syslogBuffer.empty() && i < maxRecsToSend {
+			if len(pending) > 0 && time.Now().Before(retryAt) {
+				continue
+			}
+			recs := make([]*bufferRecord, 0, maxRecsToSend)
+			recs = append(recs, pending...)
+			for !s.syslogBuffer.empty() && len(recs) < maxRecsToSend {
 				r, err := s.syslogBuffer.remove()
 				if err != nil {
 					log.Printf("cannot move remove from syslog buffer: %v", err)
 					continue
 				}
-				recs[i] = r
-				i++
+				recs = append(recs, r)
+			}
+			pending = s.toSyslogBulk(ctx, recs)
+			if len(pending) > 0 {
+				backoff = s.failover.retryBackoff(backoff, bufferSendPeriod)
+				retryAt = time.Now().Add(backoff)
+				log.Printf("cannot send %d records to syslog, retrying in %s", len(pending), backoff)
+			} else {
+				backoff = 0
 			}
-			s.toSyslogBulk(ctx, recs[0:i])
+			s.closeIdleConn(time.Now())
 
 		case <-ctx.Done():
-			recs := recs[0:0]
+			recs := pending
 			for !s.syslogBuffer.empty() {
 				r, err := s.syslogBuffer.remove()
 				if err != nil {
@@ -142,38 +534,166 @@ loop:
 				}
 				recs = append(recs, r)
 			}
-			s.toSyslogBulk(ctx, recs)
+			if lost := s.toSyslogBulk(ctx, recs); len(lost) > 0 {
+				log.Printf("cannot send %d records to syslog on close, dropping them", len(lost))
+				atomic.AddUint64(&s.stats.Dropped, uint64(len(lost)))
+			}
+			s.closeConn()
 			break loop
 		}
 	}
 }
 
-func (s *syslog) toSyslogBulk(ctx context.Context, records []*bufferRecord) {
-	if s.syslogProtocol == "" || s.syslogAddr == "" || s.syslogTag == "" || len(records) == 0 {
-		return
+// toSyslogBulk - sends records to active endpoint. Records unacknowledged because of write failure
+// are resent to next endpoints (at most one pass over them). Returns records no endpoint accepted
+func (s *syslog) toSyslogBulk(ctx context.Context, records []*bufferRecord) []*bufferRecord {
+	if s.syslogProtocol == "" || (s.syslogAddr == "" && !isLocalProtocol(s.syslogProtocol)) ||
+		s.syslogTag == "" || len(records) == 0 {
+		return nil
 	}
 	if s.dialMethod == nil {
-		return
+		return nil
 	}
-	var (
-		slog SyslogWriter
-		ok   bool
-	)
-	for {
-		slog, ok = s.dialMethod(ctx, s.syslogProtocol, s.syslogAddr, s.syslogTag)
-		if ok {
-			break
+	for pass := 0; len(records) > 0 && pass < len(s.failover.endpoints); pass++ {
+		slog, e, err := s.connection(ctx)
+		if err != nil {
+			log.Printf("cannot connect to syslog: %v", err)
+			return records
+		}
+		n, err := s.writeBulk(slog, e, records)
+		// writer reconnects by itself on write failure, but unknown writers may not,
+		// so the rest is resent over new connection to next endpoint
+		if err != nil {
+			s.writeFailure(s.connIndex)
+		}
+		if err != nil || s.idleTimeout <= 0 {
+			s.closeConn()
 		}
-		time.Sleep(1 * time.Second)
+		if n > 0 && s.failover.cfg.OnBatch != nil {
+			s.failover.cfg.OnBatch(e, n)
+		}
+		records = records[n:]
 	}
-	defer slog.Close()
+	return records
+}
 
+// writeBulk - writes records to sl of endpoint e, returns count of records written (and flushed)
+// before failure
+func (s *syslog) writeBulk(sl SyslogWriter, e Endpoint, records []*bufferRecord) (int, error) {
+	if bw, ok := sl.(BatchWriter); ok {
+		var recs []*bufferRecord
+		for _, r := range records {
+			recs = append(recs, s.prepareRecord(sl, e, r)...)
+		}
+		if err := s.toSyslogBatch(bw, recs); err != nil {
+			return 0, err
+		}
+	} else {
+		for i, r := range records {
+			for _, pr := range s.prepareRecord(sl, e, r) {
+				if err := s.toSyslogRecord(sl, pr); err != nil {
+					return i, err
+				}
+			}
+		}
+	}
+	// records are not acknowledged until they are flushed
+	if err := s.flushConn(); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// prepareRecord - returns records to send instead of r to writer sl of endpoint e
+// (see multilineRecords and limitRecord)
+func (s *syslog) prepareRecord(sl SyslogWriter, e Endpoint, r *bufferRecord) []*bufferRecord {
+	var recs []*bufferRecord
+	for _, mr := range s.multilineRecords(sl, e.Protocol, r) {
+		recs = append(recs, s.limitRecord(e.Protocol, mr)...)
+	}
+	return recs
+}
+
+// toSyslogRecord - sends record with its fields if writer supports it, just message otherwise
+func (s *syslog) toSyslogRecord(sl SyslogWriter, r *bufferRecord) error {
+	rw, ok := sl.(RecordWriter)
+	if !ok {
+		return s.toSyslog(r.ctx, sl, r.level, r.value)
+	}
+	err := rw.WriteRecord(s.formatRecord(r))
+	if errors.Is(err, format.ErrDropped) {
+		log.Printf("syslog writer dropped record: %v", err)
+		atomic.AddUint64(&s.stats.Dropped, 1)
+		return nil
+	}
+	if err != nil {
+		log.Printf("cannot send to syslog: %v", err)
+	}
+	return err
+}
+
+// toSyslogBatch - sends records with one call of batch writer bw
+func (s *syslog) toSyslogBatch(bw BatchWriter, records []*bufferRecord) error {
+	recs := make([]*format.Record, 0, len(records))
 	for _, r := range records {
-		s.toSyslog(r.ctx, slog, r.level, r.value)
+		recs = append(recs, s.formatRecord(r))
 	}
+	err := bw.WriteRecords(recs)
+	if err != nil {
+		log.Printf("cannot send to syslog: %v", err)
+	}
+	return err
+}
+
+// formatRecord - builds record of buffer record with identity, context fields and send time
+func (s *syslog) formatRecord(r *bufferRecord) *format.Record {
+	fields := FieldsFromContext(r.ctx)
+	if s.sendTimeField != "" || len(s.identity.Fields) > 0 {
+		f := make(format.Fields, len(s.identity.Fields)+len(fields)+1)
+		for k, v := range s.identity.Fields {
+			f[k] = v
+		}
+		for k, v := range fields {
+			f[k] = v
+		}
+		if s.sendTimeField != "" {
+			f[s.sendTimeField] = s.eventTime(time.Now()).Format(time.RFC3339Nano)
+		}
+		fields = f
+	}
+
+	extract := s.traceExtractor
+	if extract == nil {
+		extract = TraceFromContext
+	}
+	traceID, spanID := extract(r.ctx)
+
+	return &format.Record{
+		Priority:  r.level,
+		Timestamp: s.eventTime(r.ts),
+		Hostname:  s.identity.Hostname,
+		Tag:       s.identity.AppName,
+		ProcID:    s.identity.ProcID,
+		Message:   r.value,
+		Caller:    r.caller,
+		Fields:    fields,
+		TraceID:   traceID,
+		SpanID:    spanID,
+	}
+}
+
+// eventTime - applies timestamp precision and time zone to t
+func (s *syslog) eventTime(t time.Time) time.Time {
+	if s.tsPrecision > 0 {
+		t = t.Truncate(s.tsPrecision)
+	}
+	if s.tsLocation != nil {
+		t = t.In(s.tsLocation)
+	}
+	return t
 }
 
-func (s *syslog) toSyslog(ctx context.Context, sl SyslogWriter, lvl slog.Priority, st string) {
+func (s *syslog) toSyslog(ctx context.Context, sl SyslogWriter, lvl slog.Priority, st string) error {
 	var (
 		err error
 	)
@@ -200,22 +720,23 @@ func (s *syslog) toSyslog(ctx context.Context, sl SyslogWriter, lvl slog.Priorit
 	if err != nil {
 		log.Printf("cannot send to syslog: %v", err)
 	}
+	return err
 }
 
-func syslogDial(ctx context.Context, syslogProtocol, syslogAddr, syslogTag string) (slw SyslogWriter, ok bool) {
-	if syslogProtocol == "" || syslogAddr == "" || syslogTag == "" {
+func (s *syslog) syslogDial(ctx context.Context, syslogProtocol, syslogAddr, syslogTag string) (slw SyslogWriter, ok bool) {
+	if syslogProtocol == "" || (syslogAddr == "" && !isLocalProtocol(syslogProtocol)) || syslogTag == "" {
 		return nil, false
 	}
 	var (
 		err error
 	)
 
-	if syslogProtocol == SyslogProtocolRELP {
-		slw, err = slRelp.Dial(syslogAddr, slog.LOG_WARNING|slog.LOG_DAEMON, syslogTag, 5*time.Second)
-	} else {
-		slw, err = slog.Dial(syslogProtocol, syslogAddr, slog.LOG_WARNING|slog.LOG_DAEMON, syslogTag)
+	d := lookupTransport(syslogProtocol)
+	if d == nil {
+		log.Printf("cannot dial syslog: %v", checkProtocol(syslogProtocol))
+		return nil, false
 	}
-
+	slw, err = d.Dial(ctx, s.dialRequest(syslogProtocol, syslogAddr, syslogTag))
 	if err != nil {
 		log.Printf("cannot open dial to syslog")
 		return nil, false
@@ -1,13 +1,30 @@
 package syslog
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	slog "log/syslog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"slogger/syslog/alert"
+	"slogger/syslog/console"
+	slFile "slogger/syslog/file"
+	"slogger/syslog/format"
+	"slogger/syslog/forward"
+	"slogger/syslog/forward/forwardtest"
+	"slogger/syslog/identity"
+	"slogger/syslog/lumberjack"
+	"slogger/syslog/lumberjack/lumberjacktest"
 	"slogger/syslog/mock"
 )
 
@@ -25,7 +42,7 @@ func TestSyslog_Close(t *testing.T) {
 	mockWriter := &mock.SyslogWriter{}
 	bufSize := 1024 * 1024
 
-	s, err := New(ctx, "1", "2", "3", bufSize, 100*time.Second, bufSize/2)
+	s, err := New(ctx, SyslogProtocolTCP, "2", "3", bufSize, 100*time.Second, bufSize/2)
 	if err != nil {
 		t.Errorf("cannot create syslog sender: %v", err)
 	}
@@ -50,13 +67,25 @@ func TestSyslog_Close(t *testing.T) {
 	}
 }
 
+func TestSyslog_HeaderValidation(t *testing.T) {
+	s, err := New(context.Background(), SyslogProtocolTCP, "2", "my app", 8, time.Second, 8)
+	if err != nil {
+		t.Errorf("expect tag not validated by default, got: %v", err)
+	} else {
+		s.Close()
+	}
+	if _, err := New(context.Background(), SyslogProtocolTCP, "2", "my app", 8, time.Second, 8, WithHeaderValidation()); err == nil {
+		t.Errorf("expect error for invalid tag, got no error")
+	}
+}
+
 func TestSyslog_Send(t *testing.T) {
 	ctx := context.Background()
 	mockWriter := &mock.SyslogWriter{}
 
 	bufSize := 32 // should be even number for this test
 
-	s, err := New(ctx, "1", "2", "3", bufSize, 10*time.Millisecond, bufSize/2)
+	s, err := New(ctx, SyslogProtocolTCP, "2", "3", bufSize, 10*time.Millisecond, bufSize/2)
 	if err != nil {
 		t.Errorf("cannot create syslog sender: %v", err)
 	}
@@ -119,3 +148,346 @@ func TestSyslog_toSyslog(t *testing.T) {
 		return
 	}
 }
+
+func TestSyslog_toSyslogRecord(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), format.Fields{"request_id": "abc"})
+	ctx = ContextWithFields(ctx, format.Fields{"user": "u1"})
+	mockWriter := &mock.RecordWriter{}
+
+	s, err := New(ctx, SyslogProtocolTCP, "2", "3", 8, 100*time.Second, 8, WithCaller(0))
+	if err != nil {
+		t.Errorf("cannot create syslog sender: %v", err)
+	}
+	sl := s.(*syslog)
+	sl.SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return mockWriter, true
+	})
+
+	s.Send(ctx, slog.LOG_ERR, "Test message")
+	s.Close()
+
+	recs := mockWriter.Records()
+	if len(recs) != 1 {
+		t.Fatalf("expect 1 record, got: %d", len(recs))
+	}
+	if recs[0].Message != "Test message" || recs[0].Priority != slog.LOG_ERR {
+		t.Errorf("unexpected record: %+v", recs[0])
+	}
+	if !strings.Contains(recs[0].Caller, "sender_test.go:") {
+		t.Errorf("expect caller in sender_test.go, got: %q", recs[0].Caller)
+	}
+	if recs[0].Fields["request_id"] != "abc" || recs[0].Fields["user"] != "u1" {
+		t.Errorf("unexpected fields: %v", recs[0].Fields)
+	}
+}
+
+func TestSyslog_eventTime(t *testing.T) {
+	ctx := context.Background()
+	mockWriter := &mock.RecordWriter{}
+	loc := time.FixedZone("UTC+3", 3*60*60)
+
+	s, err := New(ctx, SyslogProtocolTCP, "2", "3", 8, 100*time.Second, 8,
+		WithTimestampPrecision(time.Millisecond), WithTimestampLocation(loc), WithSendTime("send_ts"))
+	if err != nil {
+		t.Errorf("cannot create syslog sender: %v", err)
+	}
+	sl := s.(*syslog)
+	sl.SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return mockWriter, true
+	})
+
+	before := time.Now().Truncate(time.Millisecond)
+	s.Send(ctx, slog.LOG_ERR, "Test message")
+	after := time.Now()
+	time.Sleep(50 * time.Millisecond)
+	s.Close()
+
+	recs := mockWriter.Records()
+	if len(recs) != 1 {
+		t.Fatalf("expect 1 record, got: %d", len(recs))
+	}
+	ts := recs[0].Timestamp
+	if ts.Before(before) || ts.After(after) {
+		t.Errorf("expect event time between %v and %v, got: %v", before, after, ts)
+	}
+	if ts.Nanosecond()%int(time.Millisecond) != 0 {
+		t.Errorf("expect millisecond precision, got: %v", ts)
+	}
+	if ts.Location() != loc {
+		t.Errorf("expect location %v, got: %v", loc, ts.Location())
+	}
+
+	sendTS, err := time.Parse(time.RFC3339Nano, recs[0].Fields["send_ts"].(string))
+	if err != nil {
+		t.Fatalf("cannot parse send time: %v", err)
+	}
+	if sendTS.Sub(ts) < 50*time.Millisecond {
+		t.Errorf("expect send time at least 50ms after event time, got event %v, send %v", ts, sendTS)
+	}
+}
+
+func TestSyslog_identity(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), format.Fields{"k8s_pod": "override"})
+	mockWriter := &mock.RecordWriter{}
+
+	s, err := New(ctx, SyslogProtocolTCP, "2", "3", 8, 100*time.Second, 8, WithIdentity(identity.Static{
+		Hostname: "host",
+		AppName:  "app",
+		ProcID:   "proc",
+		Fields:   format.Fields{"k8s_pod": "pod", "k8s_node": "node"},
+	}))
+	if err != nil {
+		t.Errorf("cannot create syslog sender: %v", err)
+	}
+	sl := s.(*syslog)
+	sl.SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return mockWriter, true
+	})
+
+	s.Send(ctx, slog.LOG_ERR, "Test message")
+	s.Close()
+
+	recs := mockWriter.Records()
+	if len(recs) != 1 {
+		t.Fatalf("expect 1 record, got: %d", len(recs))
+	}
+	r := recs[0]
+	if r.Hostname != "host" || r.Tag != "app" || r.ProcID != "proc" {
+		t.Errorf("unexpected record identity: %+v", r)
+	}
+	if r.Fields["k8s_pod"] != "override" || r.Fields["k8s_node"] != "node" {
+		t.Errorf("unexpected fields: %v", r.Fields)
+	}
+
+	_, err = New(ctx, SyslogProtocolTCP, "2", "3", 8, 100*time.Second, 8, WithIdentity(identity.ProviderFunc(func() (identity.Identity, error) {
+		return identity.Identity{}, fmt.Errorf("test error")
+	})))
+	if err == nil {
+		t.Errorf("expect error of identity provider, got no error")
+	}
+}
+
+func TestSyslog_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slogger")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	if err := Probe(SyslogProtocolFile, path); err != nil {
+		t.Fatalf("expect no probe error, got: %v", err)
+	}
+	s, err := New(context.Background(), SyslogProtocolFile, path, "tag", 8, 10*time.Millisecond, 8,
+		WithFormatter(format.Raw{}), WithFileConfig(slFile.Config{Sync: slFile.SyncClose}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	s.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read file: %v", err)
+	}
+	if string(b) != "first\nsecond\n" {
+		t.Errorf("unexpected file content: %q", b)
+	}
+}
+
+func TestSyslog_Console(t *testing.T) {
+	b := new(bytes.Buffer)
+	s, err := New(context.Background(), SyslogProtocolRELP, "127.0.0.1:1", "tag", 8, 10*time.Millisecond, 8,
+		WithConsole(console.Config{Output: b}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	if err := s.Probe(); err != nil {
+		t.Errorf("expect no probe error of console, got: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "Test message")
+	s.Close()
+
+	if l := b.String(); !strings.HasSuffix(l, " ERROR   tag: Test message\n") {
+		t.Errorf("unexpected console output: %q", l)
+	}
+}
+
+func TestSyslog_Loki(t *testing.T) {
+	values := make(chan int, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Streams []struct {
+				Values [][2]string `json:"values"`
+			} `json:"streams"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		n := 0
+		for _, s := range req.Streams {
+			n += len(s.Values)
+		}
+		values <- n
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := Probe(SyslogProtocolLoki, srv.URL); err != nil {
+		t.Errorf("expect no probe error, got: %v", err)
+	}
+	s, err := New(context.Background(), SyslogProtocolLoki, srv.URL, "tag", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		s.Send(context.Background(), slog.LOG_ERR, strconv.Itoa(i))
+	}
+	s.Close()
+
+	total := 0
+	for total < 5 {
+		select {
+		case n := <-values:
+			total += n
+		case <-time.After(time.Second):
+			t.Fatalf("expect 5 values pushed, got: %d", total)
+		}
+	}
+}
+
+func TestSyslog_OTLP(t *testing.T) {
+	traces := make(chan string, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceLogs []struct {
+				ScopeLogs []struct {
+					LogRecords []struct {
+						TraceID string `json:"traceId"`
+						SpanID  string `json:"spanId"`
+					} `json:"logRecords"`
+				} `json:"scopeLogs"`
+			} `json:"resourceLogs"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				for _, lr := range sl.LogRecords {
+					traces <- lr.TraceID + "/" + lr.SpanID
+				}
+			}
+		}
+	}))
+	defer srv.Close()
+
+	s, err := New(context.Background(), SyslogProtocolOTLP, srv.URL, "tag", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	ctx := ContextWithTrace(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	s.Send(ctx, slog.LOG_ERR, "Test message")
+	s.Close()
+
+	select {
+	case tr := <-traces:
+		if tr != "4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7" {
+			t.Errorf("unexpected trace context: %s", tr)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("record not exported")
+	}
+}
+
+func TestSyslog_Forward(t *testing.T) {
+	srv, err := forwardtest.NewServer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	s, err := New(context.Background(), SyslogProtocolForward, srv.Addr, "tag", 8, 10*time.Millisecond, 8,
+		WithForwardConfig(forward.Config{SharedKey: "secret", RequireAck: true}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	s.Send(context.Background(), slog.LOG_INFO, "second")
+	s.Close()
+
+	events := srv.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if events[0].Tag != "tag" || events[0].Record[forward.KeyMessage] != "first" {
+		t.Errorf("unexpected event: %v", events[0])
+	}
+	if len(srv.Chunks()) != 1 {
+		t.Errorf("expect records sent in 1 chunk, got: %v", srv.Chunks())
+	}
+}
+
+func TestSyslog_Lumberjack(t *testing.T) {
+	srv, err := lumberjacktest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	s, err := New(context.Background(), SyslogProtocolLumberjack, srv.Addr, "tag", 8, 10*time.Millisecond, 8,
+		WithLumberjackConfig(lumberjack.Config{Compression: 1}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	s.Send(context.Background(), slog.LOG_INFO, "second")
+	s.Close()
+
+	events := srv.Events()
+	if len(events) != 2 {
+		t.Fatalf("expect 2 events, got: %d", len(events))
+	}
+	if events[1]["message"] != "second" {
+		t.Errorf("unexpected event: %v", events[1])
+	}
+	if n := srv.Windows(); n != 1 {
+		t.Errorf("expect records sent in 1 window, got: %d", n)
+	}
+}
+
+func TestSyslog_Alerts(t *testing.T) {
+	bodies := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&m)
+		bodies <- m.Text
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	mockWriter := &mock.SyslogWriter{}
+	s, err := New(ctx, SyslogProtocolTCP, "2", "3", 8, 10*time.Millisecond, 8,
+		WithAlerts(alert.Config{Notifiers: []alert.Notifier{&alert.Webhook{URL: srv.URL, Format: alert.WebhookSlack}}}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.(*syslog).SetDialMethod(func(context.Context, string, string, string) (SyslogWriter, bool) {
+		return mockWriter, true
+	})
+	s.Send(ctx, slog.LOG_ERR, "not an alert")
+	s.Send(ctx, slog.LOG_CRIT, "disk full")
+	s.Send(ctx, slog.LOG_CRIT, "disk full")
+	s.Close()
+
+	select {
+	case text := <-bodies:
+		if text != "[crit] 3: disk full (x2)" {
+			t.Errorf("unexpected alert text: %q", text)
+		}
+	default:
+		t.Errorf("expect alert digest sent on close")
+	}
+	if n := mockWriter.TotalMessages(); n != 3 {
+		t.Errorf("expect all messages sent to syslog, got: %d", n)
+	}
+}
@@ -0,0 +1,251 @@
+package syslog
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SRVScheme - prefix of addresses resolved with DNS SRV records, e.g. "srv://_relp._tcp.logs.internal"
+	SRVScheme = "srv://"
+	// DefaultSRVRefresh - period of re-resolving SRV records
+	DefaultSRVRefresh = time.Minute
+)
+
+// Resolver - DNS SRV resolver, implemented by net.Resolver
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// WithResolver - set resolver of "srv://" addresses, net.DefaultResolver by default
+func WithResolver(r Resolver) Option {
+	return func(s *syslog) {
+		s.srv.resolver = r
+	}
+}
+
+// WithSRVRefresh - set period of re-resolving "srv://" addresses. Addresses are also re-resolved
+// when all their targets fail
+func WithSRVRefresh(d time.Duration) Option {
+	return func(s *syslog) {
+		s.srv.refresh = d
+	}
+}
+
+// isSRV - returns true for "srv://" address
+func isSRV(addr string) bool {
+	return strings.HasPrefix(addr, SRVScheme)
+}
+
+// srvEntry - resolved targets of SRV name
+type srvEntry struct {
+	targets  []string
+	resolved time.Time
+}
+
+// srvCache - resolved SRV addresses
+type srvCache struct {
+	resolver Resolver
+	refresh  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]srvEntry
+}
+
+// targets - returns "host:port" targets of SRV address in order of preference, resolving it
+// if it is not cached or cache is expired. If resolving fails, expired targets are used
+func (c *srvCache) targets(ctx context.Context, addr string) ([]string, error) {
+	refresh := c.refresh
+	if refresh <= 0 {
+		refresh = DefaultSRVRefresh
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[addr]
+	c.mu.Unlock()
+	if ok && time.Since(e.resolved) < refresh {
+		return e.targets, nil
+	}
+
+	targets, err := resolveSRV(ctx, c.resolver, addr)
+	if err != nil {
+		if ok {
+			log.Printf("cannot resolve %s, using previous targets: %v", addr, err)
+			return e.targets, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]srvEntry)
+	}
+	c.entries[addr] = srvEntry{targets: targets, resolved: time.Now()}
+	return targets, nil
+}
+
+// invalidate - makes address to be re-resolved on next use
+func (c *srvCache) invalidate(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[addr]; ok {
+		e.resolved = time.Time{}
+		c.entries[addr] = e
+	}
+}
+
+// resolveSRV - resolves "srv://" address into "host:port" targets ordered by RFC 2782
+func resolveSRV(ctx context.Context, r Resolver, addr string) ([]string, error) {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	_, srvs, err := r.LookupSRV(ctx, "", "", strings.TrimPrefix(addr, SRVScheme))
+	if err != nil {
+		return nil, err
+	}
+	srvs = orderSRV(srvs)
+
+	targets := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		// "." target means service is not available at this domain
+		if srv.Target == "." {
+			continue
+		}
+		targets = append(targets, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+	}
+	if len(targets) == 0 {
+		return nil, &net.DNSError{Err: "no SRV targets", Name: addr}
+	}
+	return targets, nil
+}
+
+// orderSRV - sorts records by priority and, within priority, by weighted random selection (RFC 2782)
+func orderSRV(srvs []*net.SRV) []*net.SRV {
+	res := make([]*net.SRV, len(srvs))
+	copy(res, srvs)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Priority < res[j].Priority
+	})
+
+	for start := 0; start < len(res); {
+		end := start + 1
+		for end < len(res) && res[end].Priority == res[start].Priority {
+			end++
+		}
+		shuffleByWeight(res[start:end])
+		start = end
+	}
+	return res
+}
+
+// shuffleByWeight - orders records of the same priority by weighted random selection
+func shuffleByWeight(srvs []*net.SRV) {
+	sum := 0
+	for _, srv := range srvs {
+		sum += int(srv.Weight)
+	}
+	for i := 0; i < len(srvs) && sum > 0; i++ {
+		n := rand.Intn(sum + 1)
+		for j := i; j < len(srvs); j++ {
+			n -= int(srvs[j].Weight)
+			if n <= 0 {
+				srvs[i], srvs[j] = srvs[j], srvs[i]
+				break
+			}
+		}
+		sum -= int(srvs[i].Weight)
+	}
+}
+
+// dialAddr - dials address, every target of "srv://" address in order until success
+func (s *syslog) dialAddr(ctx context.Context, protocol, addr string) (SyslogWriter, bool) {
+	if !isSRV(addr) {
+		return s.dialMethod(ctx, protocol, addr, s.syslogTag)
+	}
+
+	targets, err := s.srv.targets(ctx, addr)
+	if err != nil {
+		log.Printf("cannot resolve %s: %v", addr, err)
+		return nil, false
+	}
+	for _, t := range targets {
+		if w, ok := s.dialMethod(ctx, protocol, t, s.syslogTag); ok {
+			return w, true
+		}
+	}
+	s.srv.invalidate(addr)
+	return nil, false
+}
+
+// probeAddr - probes address, "srv://" address is reachable if any of its targets is
+func (s *syslog) probeAddr(protocol, addr string) error {
+	if !isSRV(addr) {
+		return Probe(protocol, addr)
+	}
+	targets, err := s.srv.targets(context.Background(), addr)
+	if err != nil {
+		return err
+	}
+	return probeTargets(protocol, targets)
+}
+
+// probeTargets - returns nil if any of targets is reachable
+func probeTargets(protocol string, targets []string) error {
+	var err error
+	for _, t := range targets {
+		if err = Probe(protocol, t); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// srvTargets - returns cached targets of "srv://" address, nil for other addresses
+func (s *syslog) srvTargets(ctx context.Context, addr string) []string {
+	if !isSRV(addr) {
+		return nil
+	}
+	targets, _ := s.srv.targets(ctx, addr)
+	return targets
+}
+
+// srvChanged - returns true if "srv://" address of kept connection resolves (after cache refresh)
+// to other targets than when connection was made
+func (s *syslog) srvChanged(ctx context.Context) bool {
+	if !isSRV(s.connEndpoint.Addr) {
+		return false
+	}
+	targets, err := s.srv.targets(ctx, s.connEndpoint.Addr)
+	if err != nil {
+		return false
+	}
+	return !sameTargets(targets, s.connTargets)
+}
+
+// sameTargets - returns true if a and b have the same targets in any order (order of targets
+// with equal priority is random)
+func sameTargets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]int, len(a))
+	for _, t := range a {
+		set[t]++
+	}
+	for _, t := range b {
+		if set[t] == 0 {
+			return false
+		}
+		set[t]--
+	}
+	return true
+}
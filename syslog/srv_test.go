@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"context"
+	"errors"
+	slog "log/syslog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"slogger/syslog/mock"
+)
+
+// fakeResolver - in-process DNS stand-in
+type fakeResolver struct {
+	mu      sync.Mutex
+	records map[string][]*net.SRV
+	lookups int
+}
+
+func (r *fakeResolver) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lookups++
+	srvs, ok := r.records[name]
+	if !ok {
+		return "", nil, errors.New("no such host")
+	}
+	return name, srvs, nil
+}
+
+func (r *fakeResolver) set(name string, srvs []*net.SRV) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[name] = srvs
+}
+
+func (r *fakeResolver) lookupsCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lookups
+}
+
+func Test_resolveSRV(t *testing.T) {
+	r := &fakeResolver{records: map[string][]*net.SRV{
+		"_relp._tcp.logs.internal": {
+			{Target: "logs-3.internal.", Port: 2514, Priority: 20},
+			{Target: ".", Port: 0, Priority: 5},
+			{Target: "logs-1.internal.", Port: 2514, Priority: 10},
+		},
+	}}
+
+	targets, err := resolveSRV(context.Background(), r, "srv://_relp._tcp.logs.internal")
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if len(targets) != 2 || targets[0] != "logs-1.internal:2514" || targets[1] != "logs-3.internal:2514" {
+		t.Errorf("unexpected targets: %v", targets)
+	}
+
+	if _, err := resolveSRV(context.Background(), r, "srv://_syslog._tcp.none"); err == nil {
+		t.Errorf("expect error of unknown name, got no error")
+	}
+}
+
+func Test_orderSRV(t *testing.T) {
+	srvs := []*net.SRV{
+		{Target: "light", Priority: 1, Weight: 1},
+		{Target: "heavy", Priority: 1, Weight: 99},
+		{Target: "backup", Priority: 2, Weight: 100},
+	}
+	heavyFirst := 0
+	for i := 0; i < 1000; i++ {
+		res := orderSRV(srvs)
+		if res[2].Target != "backup" {
+			t.Fatalf("expect lower priority last, got: %s", res[2].Target)
+		}
+		if res[0].Target == "heavy" {
+			heavyFirst++
+		}
+	}
+	if heavyFirst < 900 {
+		t.Errorf("expect heavy target first in most cases, got: %d of 1000", heavyFirst)
+	}
+}
+
+func TestSyslog_SRV(t *testing.T) {
+	r := &fakeResolver{records: map[string][]*net.SRV{
+		"_syslog._tcp.logs.internal": {
+			{Target: "logs-1.", Port: 514, Priority: 10},
+			{Target: "logs-2.", Port: 514, Priority: 20},
+		},
+	}}
+	// connection per batch, so every batch dials targets
+	s, err := New(context.Background(), SyslogProtocolTCP, "srv://_syslog._tcp.logs.internal", "tag", 8,
+		10*time.Millisecond, 8, WithResolver(r), WithSRVRefresh(time.Hour), WithIdleTimeout(-1))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	// batches are sent by test
+	s.Close()
+	sl := s.(*syslog)
+
+	var (
+		mu     sync.Mutex
+		dialed []string
+		down   = map[string]bool{"logs-1:514": true}
+	)
+	mockWriter := &mock.SyslogWriter{}
+	sl.SetDialMethod(func(_ context.Context, _, addr, _ string) (SyslogWriter, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		dialed = append(dialed, addr)
+		return mockWriter, !down[addr]
+	})
+
+	sl.toSyslogBulk(context.Background(), []*bufferRecord{{ctx: context.Background(), level: slog.LOG_ERR, value: "first"}})
+	sl.toSyslogBulk(context.Background(), []*bufferRecord{{ctx: context.Background(), level: slog.LOG_ERR, value: "second"}})
+	if len(dialed) != 4 || dialed[0] != "logs-1:514" || dialed[1] != "logs-2:514" {
+		t.Errorf("expect targets dialed in priority order, got: %v", dialed)
+	}
+	if n := r.lookupsCount(); n != 1 {
+		t.Errorf("expect cached targets, got %d lookups", n)
+	}
+	if c := mockWriter.Messages(slog.LOG_ERR); c != 2 {
+		t.Errorf("expect 2 messages, got: %d", c)
+	}
+
+	// all targets fail - name is re-resolved
+	mu.Lock()
+	down["logs-2:514"] = true
+	mu.Unlock()
+	if _, ok := sl.dialAddr(context.Background(), SyslogProtocolTCP, sl.syslogAddr); ok {
+		t.Errorf("expect dial failure")
+	}
+	r.set("_syslog._tcp.logs.internal", []*net.SRV{{Target: "logs-3.", Port: 514}})
+	if _, ok := sl.dialAddr(context.Background(), SyslogProtocolTCP, sl.syslogAddr); !ok {
+		t.Errorf("expect dial of re-resolved target")
+	}
+	if n := r.lookupsCount(); n != 2 {
+		t.Errorf("expect re-resolving after failure, got %d lookups", n)
+	}
+}
+
+func TestSyslog_ProbeSRV(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	r := &fakeResolver{records: map[string][]*net.SRV{
+		"_syslog._tcp.logs.internal": {
+			{Target: "127.0.0.1.", Port: 1, Priority: 10},
+			{Target: "127.0.0.1.", Port: uint16(port), Priority: 20},
+		},
+	}}
+	s, err := New(context.Background(), SyslogProtocolTCP, "srv://_syslog._tcp.logs.internal", "tag", 8,
+		time.Second, 8, WithResolver(r))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Probe(); err != nil {
+		t.Errorf("expect reachable target, got: %v", err)
+	}
+}
+
+func TestSyslog_SRVChanged(t *testing.T) {
+	r := &fakeResolver{records: map[string][]*net.SRV{
+		"_syslog._tcp.logs.internal": {{Target: "logs-1.", Port: 514}},
+	}}
+	s, err := New(context.Background(), SyslogProtocolTCP, "srv://_syslog._tcp.logs.internal", "tag", 8,
+		10*time.Millisecond, 8, WithResolver(r), WithSRVRefresh(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	// batches are sent by test
+	s.Close()
+	sl := s.(*syslog)
+
+	var dialed []string
+	sl.SetDialMethod(func(_ context.Context, _, addr, _ string) (SyslogWriter, bool) {
+		dialed = append(dialed, addr)
+		return &mock.SyslogWriter{}, true
+	})
+	send := func() {
+		sl.toSyslogBulk(context.Background(), []*bufferRecord{{ctx: context.Background(), level: slog.LOG_ERR, value: "m"}})
+	}
+
+	send()
+	send()
+	if len(dialed) != 1 {
+		t.Errorf("expect connection kept while targets are the same, got dials: %v", dialed)
+	}
+	r.set("_syslog._tcp.logs.internal", []*net.SRV{{Target: "logs-2.", Port: 514}})
+	send()
+	if len(dialed) != 2 || dialed[1] != "logs-2:514" {
+		t.Errorf("expect redial of new target, got dials: %v", dialed)
+	}
+	sl.closeConn()
+}
@@ -0,0 +1,170 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader - client certificate and CA pool loaded from PEM files. Files are reloaded
+// on handshake if they were modified, or by Reload call (e.g. on SIGHUP)
+type Reloader struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// NewReloader - loads client certificate (certFile and keyFile, may be empty if no mutual auth)
+// and CA pool (caFile, may be empty to use system roots)
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("tlsconfig: both certificate and key files should be set")
+	}
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload - loads files
+func (r *Reloader) Reload() error {
+	var (
+		cert    *tls.Certificate
+		pool    *x509.CertPool
+		modTime = r.filesModTime()
+	)
+
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("tlsconfig: cannot load client certificate: %v", err)
+		}
+		cert = &c
+	}
+	if r.caFile != "" {
+		pem, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("tlsconfig: cannot load CA: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tlsconfig: no certificates in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert, r.pool, r.modTime = cert, pool, modTime
+	return nil
+}
+
+// Config - returns copy of base which uses certificates of reloader. Server certificate is verified
+// with host name of dial address or base.ServerName, which must be set for IP addresses
+func (r *Reloader) Config(base *tls.Config) *tls.Config {
+	if base == nil {
+		base = &tls.Config{}
+	}
+	c := base.Clone()
+	if r.certFile != "" {
+		c.Certificates = nil
+		c.GetClientCertificate = r.clientCertificate
+	}
+	if r.caFile != "" {
+		// chain is verified by verifyConnection with current CA pool
+		c.InsecureSkipVerify = true
+		serverName := c.ServerName
+		next := c.VerifyConnection
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if err := r.verifyConnection(cs, serverName); err != nil {
+				return err
+			}
+			if next != nil {
+				return next(cs)
+			}
+			return nil
+		}
+	}
+	return c
+}
+
+func (r *Reloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.reloadModified()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// verifyConnection - verifies server certificate with current CA pool and server name of connection.
+// Handshake fails if server name is unknown: it is not sent for IP addresses, so serverName of
+// base config is used then
+func (r *Reloader) verifyConnection(cs tls.ConnectionState, serverName string) error {
+	r.reloadModified()
+
+	name := cs.ServerName
+	if name == "" {
+		name = serverName
+	}
+	if name == "" {
+		return errors.New("tlsconfig: no server name to verify certificate, set tls.Config.ServerName")
+	}
+
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tlsconfig: no server certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       name,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// reloadModified - reloads files if they were modified since last load
+func (r *Reloader) reloadModified() {
+	r.mu.RLock()
+	modTime := r.modTime
+	r.mu.RUnlock()
+
+	if r.filesModTime().After(modTime) {
+		// keep previous certificates if new files are broken (e.g. partially written)
+		r.Reload()
+	}
+}
+
+// filesModTime - returns latest modification time of files
+func (r *Reloader) filesModTime() time.Time {
+	var t time.Time
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		if fi, err := os.Stat(f); err == nil && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+// Package tlsconfig provides helpers to build tls.Config of syslog TLS transports:
+// server certificate pinning and reloading of certificates from files
+package tlsconfig
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Fingerprint - returns SHA-256 fingerprint of DER certificate as lowercase hex string
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// Pin - returns copy of cfg which also requires server leaf certificate to match one of
+// SHA-256 fingerprints (hex, case and ':' separators are ignored)
+func Pin(cfg *tls.Config, fingerprints ...string) (*tls.Config, error) {
+	if len(fingerprints) == 0 {
+		return nil, errors.New("tlsconfig: no fingerprints to pin")
+	}
+	pins := make(map[string]struct{}, len(fingerprints))
+	for _, fp := range fingerprints {
+		fp = strings.ToLower(strings.Replace(fp, ":", "", -1))
+		if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("tlsconfig: invalid SHA-256 fingerprint %q", fp)
+		}
+		pins[fp] = struct{}{}
+	}
+
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	c := cfg.Clone()
+	next := c.VerifyConnection
+	c.VerifyConnection = func(cs tls.ConnectionState) error {
+		if next != nil {
+			if err := next(cs); err != nil {
+				return err
+			}
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tlsconfig: no server certificate")
+		}
+		if _, ok := pins[Fingerprint(cs.PeerCertificates[0].Raw)]; !ok {
+			return errors.New("tlsconfig: server certificate does not match pinned fingerprints")
+		}
+		return nil
+	}
+	return c, nil
+}
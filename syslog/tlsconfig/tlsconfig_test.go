@@ -0,0 +1,148 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/tlsconfig/tlstest"
+)
+
+// handshake - starts TLS server with cfg and returns error of client handshake with clientCfg
+func handshake(t *testing.T, cfg, clientCfg *tls.Config) error {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.(*tls.Conn).Handshake()
+		c.Close()
+	}()
+
+	c, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	// TLS 1.3 client certificate is checked by server after client handshake is done
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = c.Read(make([]byte, 1))
+	if err != nil && !strings.Contains(err.Error(), "EOF") {
+		return err
+	}
+	return nil
+}
+
+func testPKI(t *testing.T) (*tlstest.CA, *tlstest.Certificate, *tlstest.Certificate) {
+	ca, err := tlstest.NewCA("test CA")
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	server, err := ca.Issue("server", false)
+	if err != nil {
+		t.Fatalf("cannot issue server certificate: %v", err)
+	}
+	client, err := ca.Issue("client", true)
+	if err != nil {
+		t.Fatalf("cannot issue client certificate: %v", err)
+	}
+	return ca, server, client
+}
+
+func TestPin(t *testing.T) {
+	ca, server, _ := testPKI(t)
+	serverCfg := tlstest.ServerConfig(server, nil)
+	base := &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}
+
+	fp := Fingerprint(server.TLS.Certificate[0])
+	cfg, err := Pin(base, strings.ToUpper(fp[:2])+":"+fp[2:])
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if err := handshake(t, serverCfg, cfg); err != nil {
+		t.Errorf("expect handshake with pinned certificate, got: %v", err)
+	}
+
+	cfg, err = Pin(base, Fingerprint(ca.Cert.Raw))
+	if err != nil {
+		t.Fatalf("expect no error, got: %v", err)
+	}
+	if err := handshake(t, serverCfg, cfg); err == nil {
+		t.Errorf("expect handshake error for not pinned certificate, got no error")
+	}
+
+	if _, err := Pin(base, "abc"); err == nil {
+		t.Errorf("expect error for invalid fingerprint, got no error")
+	}
+}
+
+func TestReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, server, client := testPKI(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	write := func(name string, b []byte, modTime time.Time) {
+		if err := ioutil.WriteFile(name, b, 0600); err != nil {
+			t.Fatalf("cannot write %s: %v", name, err)
+		}
+		os.Chtimes(name, modTime, modTime)
+	}
+	now := time.Now()
+	write(certFile, client.CertPEM, now)
+	write(keyFile, client.KeyPEM, now)
+	write(caFile, ca.PEM, now)
+
+	r, err := NewReloader(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("cannot create reloader: %v", err)
+	}
+	cfg := r.Config(&tls.Config{ServerName: "localhost"})
+
+	if err := handshake(t, tlstest.ServerConfig(server, ca), cfg); err != nil {
+		t.Errorf("expect mutual TLS handshake, got: %v", err)
+	}
+
+	// server of other CA is not trusted
+	otherCA, otherServer, otherClient := testPKI(t)
+	if err := handshake(t, tlstest.ServerConfig(otherServer, otherCA), cfg); err == nil {
+		t.Errorf("expect handshake error with server of other CA, got no error")
+	}
+
+	// rotate certificates to other CA without recreating config
+	later := now.Add(time.Minute)
+	write(certFile, otherClient.CertPEM, later)
+	write(keyFile, otherClient.KeyPEM, later)
+	write(caFile, otherCA.PEM, later)
+	if err := handshake(t, tlstest.ServerConfig(otherServer, otherCA), cfg); err != nil {
+		t.Errorf("expect handshake with reloaded certificates, got: %v", err)
+	}
+	if err := handshake(t, tlstest.ServerConfig(server, ca), cfg); err == nil {
+		t.Errorf("expect handshake error with server of old CA, got no error")
+	}
+
+	// IP address is not sent as server name, handshake fails closed without ServerName
+	if err := handshake(t, tlstest.ServerConfig(otherServer, otherCA), r.Config(nil)); err == nil {
+		t.Errorf("expect handshake error without server name, got no error")
+	}
+	if err := handshake(t, tlstest.ServerConfig(otherServer, otherCA), r.Config(&tls.Config{ServerName: "127.0.0.1"})); err != nil {
+		t.Errorf("expect handshake with IP server name, got: %v", err)
+	}
+
+	if _, err := NewReloader(certFile, "", ""); err == nil {
+		t.Errorf("expect error without key file, got no error")
+	}
+}
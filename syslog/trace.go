@@ -0,0 +1,34 @@
+package syslog
+
+import "context"
+
+type traceCtxKey struct{}
+
+type traceContext struct {
+	traceID, spanID string
+}
+
+// TraceExtractor - returns hex trace and span IDs of ctx, empty if ctx has no trace
+type TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+// ContextWithTrace - returns copy of ctx with hex trace (32 digits) and span (16 digits) IDs attached
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceCtxKey{}, traceContext{traceID: traceID, spanID: spanID})
+}
+
+// TraceFromContext - returns trace and span IDs attached to ctx with ContextWithTrace
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	if ctx == nil {
+		return "", ""
+	}
+	tc, _ := ctx.Value(traceCtxKey{}).(traceContext)
+	return tc.traceID, tc.spanID
+}
+
+// WithTraceExtractor - set function extracting trace context of records (e.g. from OpenTelemetry span
+// of ctx), TraceFromContext by default
+func WithTraceExtractor(f TraceExtractor) Option {
+	return func(s *syslog) {
+		s.traceExtractor = f
+	}
+}
@@ -0,0 +1,460 @@
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	slog "log/syslog"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"slogger/syslog/console"
+	slFile "slogger/syslog/file"
+	"slogger/syslog/format"
+	"slogger/syslog/forward"
+	"slogger/syslog/gelf"
+	"slogger/syslog/journald"
+	"slogger/syslog/loki"
+	"slogger/syslog/lumberjack"
+	"slogger/syslog/otlp"
+	slRelp "slogger/syslog/relp"
+)
+
+// DialRequest - parameters of writer dialed by Dialer
+type DialRequest struct {
+	// Protocol - protocol name the dialer is registered with
+	Protocol string
+	// Addr - address of endpoint (single target of "srv://" address)
+	Addr string
+	Tag  string
+	// Priority - default priority of writer (used by Write)
+	Priority slog.Priority
+	// Formatter - formatter of WithFormatter, writers of syslog messages should use it
+	Formatter format.Formatter
+	// TLSConfig - config of WithTLSConfig, may be nil
+	TLSConfig *tls.Config
+	// Timeout - timeout of connecting of relp transports, DefaultDialTimeout if 0
+	Timeout time.Duration
+	// Multiline - policy of WithMultiline, writers of syslog messages choose framing by it
+	Multiline MultilinePolicy
+
+	// Settings of built-in transports set with sender options (WithGELFCompression, WithRELPPool,
+	// WithConsole, WithLokiConfig...), zero values if not set
+	GELFCompression gelf.Compression
+	RELPPool        *slRelp.PoolConfig
+	Console         console.Config
+	Loki            loki.Config
+	OTLP            otlp.Config
+	Forward         forward.Config
+	Lumberjack      lumberjack.Config
+	File            slFile.Config
+}
+
+// DefaultDialTimeout - timeout of connecting of built-in transports
+const DefaultDialTimeout = 5 * time.Second
+
+// timeout - returns dial timeout of request
+func (r DialRequest) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return DefaultDialTimeout
+	}
+	return r.Timeout
+}
+
+// Dialer - opens writer of custom transport. Writer is kept between batches (see WithIdleTimeout);
+// it may implement RecordWriter, BatchWriter and ConnChecker. Dialer may implement Prober,
+// NetworkDialer, SizeLimitDialer and LocalDialer
+type Dialer interface {
+	Dial(ctx context.Context, req DialRequest) (SyslogWriter, error)
+}
+
+// Prober - optional interface of Dialer which checks that address is reachable (see Probe).
+// Probe of Dialer without it opens and closes writer
+type Prober interface {
+	Probe(addr string) error
+}
+
+// NetworkDialer - optional interface of Dialer, returns network of transport ("tcp", "udp", "unix"...),
+// see ProtocolNetwork
+type NetworkDialer interface {
+	Network() string
+}
+
+// SizeLimitDialer - optional interface of Dialer, returns maximum message size of transport
+// (0 - no limit), used unless WithMaxMessageSize is set
+type SizeLimitDialer interface {
+	MaxMessageSize() int
+}
+
+// LocalDialer - optional interface of Dialer of transports with default local destination (e.g. local
+// syslog socket), address of their endpoints may be empty if Local returns true
+type LocalDialer interface {
+	Local() bool
+}
+
+// DialerFunc - adapter to use ordinary functions as Dialer
+type DialerFunc func(ctx context.Context, req DialRequest) (SyslogWriter, error)
+
+// Dial - calls f(ctx, req)
+func (f DialerFunc) Dial(ctx context.Context, req DialRequest) (SyslogWriter, error) {
+	return f(ctx, req)
+}
+
+var (
+	transportsMu sync.RWMutex
+	transports   = make(map[string]Dialer)
+)
+
+// RegisterTransport - makes transport available by protocol name to New, WithEndpoints and Probe.
+// Built-in protocols are registered the same way, registered transport replaces built-in one of the
+// same name (use LookupTransport to keep and restore it); nil dialer removes registration.
+// RegisterTransport panics if name is empty
+func RegisterTransport(name string, d Dialer) {
+	if name == "" {
+		panic("syslog: RegisterTransport with empty name")
+	}
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	if d == nil {
+		delete(transports, name)
+		return
+	}
+	transports[name] = d
+}
+
+// LookupTransport - returns dialer of protocol, nil if it is not registered
+func LookupTransport(name string) Dialer {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+
+	return transports[name]
+}
+
+// Transports - returns sorted names of registered (including built-in) transports
+func Transports() []string {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// netNetworks - networks of net package dialed as syslog over network of the same name if they are
+// not registered as protocols
+var netNetworks = map[string]bool{"tcp4": true, "tcp6": true, "udp4": true, "udp6": true}
+
+// lookupTransport - returns dialer of protocol, nil if it is unknown. Not registered networks of
+// net package (e.g. "tcp4") are dialed as syslog over that network
+func lookupTransport(name string) Dialer {
+	if d := LookupTransport(name); d != nil {
+		return d
+	}
+	if netNetworks[name] {
+		return &transport{dial: dialNetwork, network: name}
+	}
+	return nil
+}
+
+// checkProtocol - returns error if protocol is unknown
+func checkProtocol(name string) error {
+	if lookupTransport(name) == nil {
+		return fmt.Errorf("unknown syslog protocol %q", name)
+	}
+	return nil
+}
+
+// probeTransport - probes address with dialer
+func probeTransport(d Dialer, protocol, addr string) error {
+	if d == nil {
+		return checkProtocol(protocol)
+	}
+	if p, ok := d.(Prober); ok {
+		return p.Probe(addr)
+	}
+	w, err := d.Dial(context.Background(), DialRequest{
+		Protocol:  protocol,
+		Addr:      addr,
+		Tag:       "probe",
+		Priority:  dialPriority,
+		Formatter: format.Default,
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// dialPriority - priority of writers dialed by sender, their facility is set to records
+const dialPriority = slog.LOG_WARNING | slog.LOG_DAEMON
+
+// dialRequest - returns dial request with sender settings
+func (s *syslog) dialRequest(protocol, addr, tag string) DialRequest {
+	return DialRequest{
+		Protocol:  protocol,
+		Addr:      addr,
+		Tag:       tag,
+		Priority:  dialPriority,
+		Formatter: s.formatter,
+		TLSConfig: s.tlsConfig,
+		Multiline: s.multiline,
+
+		GELFCompression: s.gelfCompression,
+		RELPPool:        s.relpPool,
+		Console:         s.consoleConfig,
+		Loki:            s.lokiConfig,
+		OTLP:            s.otlpConfig,
+		Forward:         s.forwardConfig,
+		Lumberjack:      s.lumberjackConfig,
+		File:            s.fileConfig,
+	}
+}
+
+// transport - built-in transport. It implements Dialer, Prober, NetworkDialer, SizeLimitDialer
+// and LocalDialer
+type transport struct {
+	dial func(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error)
+	// network - network of connections
+	network string
+	// tls - connections are made over TLS
+	tls     bool
+	maxSize int
+	local   bool
+	// probe - checks address, dial of network if nil
+	probe func(addr string) error
+}
+
+// Dial - implements Dialer
+func (t *transport) Dial(ctx context.Context, req DialRequest) (SyslogWriter, error) {
+	if req.Formatter == nil {
+		req.Formatter = format.Default
+	}
+	return t.dial(ctx, t, req)
+}
+
+// Probe - implements Prober
+func (t *transport) Probe(addr string) error {
+	if t.probe != nil {
+		return t.probe(addr)
+	}
+	return probeNetwork(t.network, addr)
+}
+
+// Network - implements NetworkDialer
+func (t *transport) Network() string {
+	return t.network
+}
+
+// MaxMessageSize - implements SizeLimitDialer
+func (t *transport) MaxMessageSize() int {
+	return t.maxSize
+}
+
+// Local - implements LocalDialer
+func (t *transport) Local() bool {
+	return t.local
+}
+
+func init() {
+	for name, t := range map[string]*transport{
+		SyslogProtocolTCP:           {dial: dialNetwork, network: "tcp", maxSize: MaxMessageSizeTCP},
+		SyslogProtocolUDP:           {dial: dialNetwork, network: "udp", maxSize: MaxMessageSizeUDP},
+		SyslogProtocolTLS:           {dial: dialNetwork, network: "tcp", tls: true, maxSize: MaxMessageSizeTLS},
+		SyslogProtocolUnix:          {dial: dialUnix, network: "unix", maxSize: MaxMessageSizeUnix, local: true, probe: probeUnix("unix")},
+		SyslogProtocolUnixgram:      {dial: dialUnix, network: "unixgram", maxSize: MaxMessageSizeUnix, local: true, probe: probeUnix("unixgram")},
+		SyslogProtocolRELP:          {dial: dialRELP, network: "tcp", maxSize: MaxMessageSizeRELP},
+		SyslogProtocolRELPTLS:       {dial: dialRELP, network: "tcp", tls: true, maxSize: MaxMessageSizeRELP},
+		SyslogProtocolGELFUDP:       {dial: dialGELF, network: "udp", maxSize: MaxMessageSizeGELFUDP},
+		SyslogProtocolGELFTCP:       {dial: dialGELF, network: "tcp", maxSize: MaxMessageSizeGELFTCP},
+		SyslogProtocolConsole:       {dial: dialConsole, local: true, probe: func(string) error { return nil }},
+		SyslogProtocolLoki:          {dial: dialLoki, network: "tcp", probe: probeURL},
+		SyslogProtocolOTLP:          {dial: dialOTLP, network: "tcp", probe: probeURL},
+		SyslogProtocolForward:       {dial: dialForward, network: "tcp"},
+		SyslogProtocolForwardUnix:   {dial: dialForward, network: "unix"},
+		SyslogProtocolLumberjack:    {dial: dialLumberjack, network: "tcp"},
+		SyslogProtocolLumberjackTLS: {dial: dialLumberjack, network: "tcp", tls: true},
+		SyslogProtocolFile:          {dial: dialFile, probe: probeFile},
+		SyslogProtocolJournald:      {dial: dialJournald, network: "unixgram", local: true, probe: probeJournald},
+	} {
+		RegisterTransport(name, t)
+	}
+}
+
+// probeNetwork - checks that address is reachable by network
+func probeNetwork(network, addr string) error {
+	conn, err := net.DialTimeout(network, addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeUnix - returns probe of local syslog socket, the first existing one if address is empty
+func probeUnix(network string) func(addr string) error {
+	return func(addr string) error {
+		addr, err := unixSocketPath(addr)
+		if err != nil {
+			return err
+		}
+		return probeNetwork(network, addr)
+	}
+}
+
+// probeURL - checks that host of http(s) URL is reachable
+func probeURL(rawURL string) error {
+	addr, err := urlHostPort(rawURL)
+	if err != nil {
+		return err
+	}
+	return probeNetwork("tcp", addr)
+}
+
+// probeFile - checks that file can be opened for writing
+func probeFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, slFile.DefaultPerm)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// probeJournald - checks journald socket, DefaultSocket if address is empty
+func probeJournald(addr string) error {
+	if addr == "" {
+		addr = journald.DefaultSocket
+	}
+	return probeNetwork("unixgram", addr)
+}
+
+// tlsConfig - returns TLS config of request, empty one if it is not set
+func tlsConfig(req DialRequest) *tls.Config {
+	if req.TLSConfig == nil {
+		return &tls.Config{}
+	}
+	return req.TLSConfig
+}
+
+func dialRELP(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	if req.RELPPool != nil {
+		return dialRELPPool(t, req)
+	}
+	var (
+		c   *slRelp.Client
+		err error
+	)
+	if t.tls {
+		c, err = slRelp.DialTLS(req.Addr, req.Priority, req.Tag, req.timeout(), req.TLSConfig)
+	} else {
+		c, err = slRelp.Dial(req.Addr, req.Priority, req.Tag, req.timeout())
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.SetFormatter(req.Formatter)
+	return c, nil
+}
+
+// dialRELPPool - dials pool of WithRELPPool
+func dialRELPPool(t *transport, req DialRequest) (SyslogWriter, error) {
+	cfg := *req.RELPPool
+	if len(cfg.Addrs) == 0 {
+		cfg.Addrs = []string{req.Addr}
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = req.timeout()
+	}
+	if t.tls && cfg.TLSConfig == nil {
+		cfg.TLSConfig = tlsConfig(req)
+	}
+	p, err := slRelp.DialPool(cfg, req.Priority, req.Tag)
+	if err != nil {
+		return nil, err
+	}
+	p.SetFormatter(req.Formatter)
+	return p, nil
+}
+
+func dialGELF(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	w, err := gelf.Dial(t.network, req.Addr, req.Priority, req.Tag)
+	if err != nil {
+		return nil, err
+	}
+	w.SetCompression(req.GELFCompression)
+	return w, nil
+}
+
+func dialConsole(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	cfg := req.Console
+	if cfg.Output == nil && req.Addr == "stdout" {
+		cfg.Output = os.Stdout
+	}
+	return console.New(req.Priority, req.Tag, cfg), nil
+}
+
+func dialLoki(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	w, err := loki.New(req.Addr, req.Priority, req.Tag, req.Loki)
+	if err != nil {
+		return nil, err
+	}
+	w.SetContext(ctx)
+	return w, nil
+}
+
+func dialOTLP(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	w, err := otlp.New(req.Addr, req.Priority, req.Tag, req.OTLP)
+	if err != nil {
+		return nil, err
+	}
+	w.SetContext(ctx)
+	return w, nil
+}
+
+func dialForward(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	return forward.Dial(t.network, req.Addr, req.Priority, req.Tag, req.Forward)
+}
+
+func dialLumberjack(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	cfg := req.Lumberjack
+	if t.tls && cfg.TLSConfig == nil {
+		cfg.TLSConfig = tlsConfig(req)
+	}
+	return lumberjack.Dial(req.Addr, req.Priority, req.Tag, cfg)
+}
+
+func dialFile(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	w, err := slFile.Open(req.Addr, req.Priority, req.Tag, req.File)
+	if err != nil {
+		return nil, err
+	}
+	w.SetFormatter(req.Formatter)
+	return w, nil
+}
+
+func dialJournald(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	return journald.Dial(req.Addr, req.Priority, req.Tag)
+}
+
+func dialUnix(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	addr, err := unixSocketPath(req.Addr)
+	if err != nil {
+		return nil, err
+	}
+	// local syslog daemons read unix stream socket line by line, see multilinePolicy
+	return dialNet(t.network, addr, req.Priority, req.Tag, req.Formatter, FramingNonTransparent, nil)
+}
+
+// dialNetwork - dials tcp, udp or tls syslog server
+func dialNetwork(ctx context.Context, t *transport, req DialRequest) (SyslogWriter, error) {
+	network := t.network
+	if t.tls {
+		network = SyslogProtocolTLS
+	}
+	return dialNet(network, req.Addr, req.Priority, req.Tag, req.Formatter, framing(req.Multiline), req.TLSConfig)
+}
@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"context"
+	"errors"
+	slog "log/syslog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	"slogger/syslog/gelf"
+	"slogger/syslog/loki"
+	"slogger/syslog/mock"
+	"slogger/syslog/relp/relptest"
+)
+
+func TestRegisterTransport(t *testing.T) {
+	mockWriter := &mock.RecordWriter{}
+	var (
+		mu   sync.Mutex
+		reqs []DialRequest
+	)
+	RegisterTransport("fake", DialerFunc(func(ctx context.Context, req DialRequest) (SyslogWriter, error) {
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+		return mockWriter, nil
+	}))
+	defer RegisterTransport("fake", nil)
+
+	s, err := New(context.Background(), "fake", "fake:1", "tag", 8, 10*time.Millisecond, 8, WithFormatter(format.Raw{}),
+		WithMultiline(MultilineKeep), WithGELFCompression(gelf.CompressionZlib), WithLokiConfig(loki.Config{TenantID: "t1"}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	if err := s.Probe(); err != nil {
+		t.Errorf("expect probe by dialing registered transport, got: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "Test message")
+	s.Close()
+
+	if n := mockWriter.RecordsCount(slog.LOG_ERR); n != 1 {
+		t.Errorf("expect 1 record sent with registered transport, got: %d", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 2 {
+		t.Fatalf("expect 2 dials (probe and batch), got: %d", len(reqs))
+	}
+	req := reqs[1]
+	if req.Protocol != "fake" || req.Addr != "fake:1" || req.Tag != "tag" {
+		t.Errorf("unexpected dial request: %+v", req)
+	}
+	if _, ok := req.Formatter.(format.Raw); !ok {
+		t.Errorf("expect formatter of sender, got: %T", req.Formatter)
+	}
+	// settings of built-in transports are available to registered ones
+	if req.Multiline != MultilineKeep || req.GELFCompression != gelf.CompressionZlib || req.Loki.TenantID != "t1" {
+		t.Errorf("expect sender settings in dial request, got: %+v", req)
+	}
+}
+
+func TestNew_UnknownProtocol(t *testing.T) {
+	if _, err := New(context.Background(), "rlep", "127.0.0.1:1", "tag", 8, time.Second, 8); err == nil {
+		t.Errorf("expect error of unknown protocol, got no error")
+	}
+	if _, err := New(context.Background(), SyslogProtocolRELP, "127.0.0.1:1", "tag", 8, time.Second, 8,
+		WithEndpoints(Endpoint{Protocol: "rlep", Addr: "127.0.0.1:2"})); err == nil {
+		t.Errorf("expect error of unknown endpoint protocol, got no error")
+	}
+	if err := Probe("rlep", "127.0.0.1:1"); err == nil {
+		t.Errorf("expect probe error of unknown protocol, got no error")
+	}
+	s, err := New(context.Background(), "tcp4", "127.0.0.1:1", "tag", 8, time.Second, 8)
+	if err != nil {
+		t.Fatalf("expect network of net package accepted, got: %v", err)
+	}
+	s.Close()
+}
+
+// prober - dialer with Prober
+type prober struct {
+	DialerFunc
+	err error
+}
+
+func (p prober) Probe(addr string) error {
+	return p.err
+}
+
+func TestRegisterTransport_Override(t *testing.T) {
+	builtin := LookupTransport(SyslogProtocolRELP)
+	if builtin == nil {
+		t.Fatalf("expect built-in relp transport registered")
+	}
+	probeErr := errors.New("unreachable")
+	RegisterTransport(SyslogProtocolRELP, prober{
+		DialerFunc: func(ctx context.Context, req DialRequest) (SyslogWriter, error) {
+			return &mock.SyslogWriter{}, nil
+		},
+		err: probeErr,
+	})
+	if err := Probe(SyslogProtocolRELP, "127.0.0.1:1"); err != probeErr {
+		t.Errorf("expect error of registered prober, got: %v", err)
+	}
+
+	found := false
+	for _, name := range Transports() {
+		found = found || name == SyslogProtocolRELP
+	}
+	if !found {
+		t.Errorf("expect relp in transports, got: %v", Transports())
+	}
+
+	RegisterTransport(SyslogProtocolRELP, builtin)
+	if LookupTransport(SyslogProtocolRELP) != builtin {
+		t.Errorf("expect built-in relp transport restored")
+	}
+}
+
+// sizedDialer - dialer with network, message size limit and local destination
+type sizedDialer struct {
+	DialerFunc
+}
+
+func (sizedDialer) Network() string {
+	return SyslogProtocolUDP
+}
+
+func (sizedDialer) MaxMessageSize() int {
+	return 100
+}
+
+func (sizedDialer) Local() bool {
+	return true
+}
+
+func TestRegisterTransport_Optional(t *testing.T) {
+	for _, tc := range []struct {
+		protocol string
+		network  string
+		size     int
+		local    bool
+	}{
+		{SyslogProtocolRELP, SyslogProtocolTCP, MaxMessageSizeRELP, false},
+		{SyslogProtocolGELFUDP, SyslogProtocolUDP, MaxMessageSizeGELFUDP, false},
+		{SyslogProtocolUnixgram, SyslogProtocolUnixgram, MaxMessageSizeUnix, true},
+		{SyslogProtocolJournald, SyslogProtocolUnixgram, 0, true},
+		{"tcp4", "tcp4", 0, false},
+	} {
+		if n := ProtocolNetwork(tc.protocol); n != tc.network {
+			t.Errorf("%s: expect network %s, got: %s", tc.protocol, tc.network, n)
+		}
+		if m := defaultMaxMessageSize(tc.protocol); m != tc.size {
+			t.Errorf("%s: expect max size %d, got: %d", tc.protocol, tc.size, m)
+		}
+		if l := isLocalProtocol(tc.protocol); l != tc.local {
+			t.Errorf("%s: expect local %v, got: %v", tc.protocol, tc.local, l)
+		}
+	}
+
+	dial := DialerFunc(func(ctx context.Context, req DialRequest) (SyslogWriter, error) {
+		return &mock.SyslogWriter{}, nil
+	})
+	RegisterTransport("plain", dial)
+	defer RegisterTransport("plain", nil)
+	RegisterTransport("sized", sizedDialer{dial})
+	defer RegisterTransport("sized", nil)
+
+	if isLocalProtocol("plain") || ProtocolNetwork("plain") != "plain" || defaultMaxMessageSize("plain") != 0 {
+		t.Errorf("expect registered transport without optional methods to be remote with no size limit")
+	}
+	if !isLocalProtocol("sized") || ProtocolNetwork("sized") != SyslogProtocolUDP || defaultMaxMessageSize("sized") != 100 {
+		t.Errorf("expect optional methods of registered transport used")
+	}
+}
+
+func TestRegisterTransport_Wrap(t *testing.T) {
+	srv, err := relptest.NewServer()
+	if err != nil {
+		t.Fatalf("cannot start server: %v", err)
+	}
+	defer srv.Close()
+
+	builtin := LookupTransport(SyslogProtocolRELP)
+	var dials int32
+	RegisterTransport("relp-counted", DialerFunc(func(ctx context.Context, req DialRequest) (SyslogWriter, error) {
+		atomic.AddInt32(&dials, 1)
+		return builtin.Dial(ctx, req)
+	}))
+	defer RegisterTransport("relp-counted", nil)
+
+	s, err := New(context.Background(), "relp-counted", srv.Addr, "tag", 8, 10*time.Millisecond, 8)
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "Test message")
+	s.Close()
+
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("expect 1 dial, got: %d", n)
+	}
+	if n := len(srv.Messages()); n != 1 {
+		t.Errorf("expect message sent by wrapped built-in transport, got: %d", n)
+	}
+}
@@ -0,0 +1,54 @@
+package syslog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// unixSocketPaths - local syslog sockets checked by protocols unix and unixgram if address is empty
+var unixSocketPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+const (
+	// noBufsRetries - count of retries of datagram write failed with ENOBUFS
+	noBufsRetries = 5
+	noBufsDelay   = 10 * time.Millisecond
+)
+
+// isUnixProtocol - returns true for local socket protocols
+func isUnixProtocol(syslogProtocol string) bool {
+	return syslogProtocol == SyslogProtocolUnix || syslogProtocol == SyslogProtocolUnixgram
+}
+
+// isLocalProtocol - returns true for protocols with default local destination (address may be empty),
+// see LocalDialer
+func isLocalProtocol(syslogProtocol string) bool {
+	d, ok := lookupTransport(syslogProtocol).(LocalDialer)
+	return ok && d.Local()
+}
+
+// unixSocketPath - returns syslogAddr or, if it is empty, first existing local syslog socket
+func unixSocketPath(syslogAddr string) (string, error) {
+	if syslogAddr != "" {
+		return syslogAddr, nil
+	}
+	for _, p := range unixSocketPaths {
+		if fi, err := os.Stat(p); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			return p, nil
+		}
+	}
+	return "", errors.New("local syslog socket not found")
+}
+
+// isNoBufs - returns true if err is ENOBUFS (datagram socket queue is full) or EAGAIN
+func isNoBufs(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.ENOBUFS || err == syscall.EAGAIN
+}
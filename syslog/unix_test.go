@@ -0,0 +1,184 @@
+package syslog
+
+import (
+	"context"
+	"io/ioutil"
+	slog "log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+)
+
+func tempSocketDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "slogger")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	return dir
+}
+
+// listenUnixgram - starts datagram server on socket path storing messages to srv
+func listenUnixgram(t *testing.T, path string, srv *testServer) net.PacketConn {
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	go func() {
+		b := make([]byte, 65536)
+		for {
+			n, _, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			srv.add(string(b[:n]))
+		}
+	}()
+	return pc
+}
+
+// listenUnix - starts stream server on socket path storing non-transparent frames to srv
+func listenUnix(t *testing.T, path string, srv *testServer) net.Listener {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go readFrames(conn, FramingNonTransparent, srv.add)
+		}
+	}()
+	return ln
+}
+
+func TestNetWriter_Unixgram(t *testing.T) {
+	dir := tempSocketDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	srv := &testServer{}
+	pc := listenUnixgram(t, path, srv)
+
+	w, err := dialNet(SyslogProtocolUnixgram, path, slog.LOG_WARNING|slog.LOG_DAEMON, "tag", nil, FramingNonTransparent, nil)
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Err("first"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+	msgs := srv.waitMessages(1)
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0], "<27>") || !strings.HasSuffix(msgs[0], "]: first") {
+		t.Fatalf("unexpected messages: %q", msgs)
+	}
+	// "<27>Mmm dd hh:mm:ss tag[pid]: first" - local message has no hostname
+	if f := strings.Fields(msgs[0]); len(f) != 5 || !strings.HasPrefix(f[3], "tag[") {
+		t.Errorf("expect local message without hostname, got: %q", msgs[0])
+	}
+
+	// syslog daemon restart: socket is removed and created again
+	pc.Close()
+	os.Remove(path)
+	pc = listenUnixgram(t, path, srv)
+	defer pc.Close()
+
+	if err := w.Err("second"); err != nil {
+		t.Errorf("expect no error after socket recreation, got: %v", err)
+	}
+	if msgs := srv.waitMessages(2); len(msgs) != 2 || !strings.HasSuffix(msgs[1], ": second") {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
+
+func TestSyslog_Unix(t *testing.T) {
+	dir := tempSocketDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	srv := &testServer{}
+	ln := listenUnix(t, path, srv)
+	defer ln.Close()
+
+	if err := Probe(SyslogProtocolUnix, path); err != nil {
+		t.Errorf("expect no probe error, got: %v", err)
+	}
+	s, err := New(context.Background(), SyslogProtocolUnix, path, "tag", 8, 10*time.Millisecond, 8,
+		WithFormatter(format.Raw{}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "first")
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	s.Close()
+
+	if msgs := srv.waitMessages(2); strings.Join(msgs, ",") != "first,second" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
+
+func TestSyslog_UnixMultilineKeep(t *testing.T) {
+	dir := tempSocketDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+
+	srv := &testServer{}
+	ln := listenUnix(t, path, srv)
+	defer ln.Close()
+
+	s, err := New(context.Background(), SyslogProtocolUnix, path, "tag", 8, 10*time.Millisecond, 8,
+		WithFormatter(format.Raw{}), WithMultiline(MultilineKeep))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "first\nsecond")
+	s.Send(context.Background(), slog.LOG_ERR, "third")
+	s.Close()
+
+	// no octet-counting on local socket, line breaks are escaped
+	if msgs := srv.waitMessages(2); strings.Join(msgs, ",") != "first#012second,third" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
+
+func Test_unixSocketPath(t *testing.T) {
+	dir := tempSocketDir(t)
+	defer os.RemoveAll(dir)
+
+	defer func(paths []string) {
+		unixSocketPaths = paths
+	}(unixSocketPaths)
+
+	file := filepath.Join(dir, "file")
+	ioutil.WriteFile(file, nil, 0644)
+	socket := filepath.Join(dir, "log")
+	pc, err := net.ListenPacket("unixgram", socket)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer pc.Close()
+
+	unixSocketPaths = []string{filepath.Join(dir, "none"), file, socket}
+	if p, err := unixSocketPath(""); err != nil || p != socket {
+		t.Errorf("expect %s, got: %s, %v", socket, p, err)
+	}
+	if p, err := unixSocketPath("/custom"); err != nil || p != "/custom" {
+		t.Errorf("expect /custom, got: %s, %v", p, err)
+	}
+	if err := Probe(SyslogProtocolUnixgram, ""); err != nil {
+		t.Errorf("expect no probe error, got: %v", err)
+	}
+
+	unixSocketPaths = []string{file}
+	if _, err := unixSocketPath(""); err == nil {
+		t.Errorf("expect error without sockets, got no error")
+	}
+}
@@ -0,0 +1,247 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	slog "log/syslog"
+
+	"slogger/syslog/conncheck"
+	"slogger/syslog/format"
+)
+
+const (
+	severityMask = 0x07
+	facilityMask = 0xf8
+)
+
+// Framing - message framing of stream transports (RFC 6587)
+type Framing int
+
+const (
+	// FramingNonTransparent - message terminated by LF, message must not contain LF
+	FramingNonTransparent Framing = iota
+	// FramingOctetCounting - message prefixed by its length: "MSG-LEN SP SYSLOG-MSG"
+	FramingOctetCounting
+)
+
+// netWriter - SyslogWriter for plain tcp, udp, tls and local unix socket transports.
+// Unlike log/syslog.Writer it builds messages with pluggable formatter
+type netWriter struct {
+	priority  slog.Priority
+	tag       string
+	hostname  string
+	network   string
+	raddr     string
+	formatter format.Formatter
+	framing   Framing
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// dialNet - like log/syslog.Dial but with formatter. tlsConfig is used by tls network only
+func dialNet(network, raddr string, priority slog.Priority, tag string, formatter format.Formatter, framing Framing,
+	tlsConfig *tls.Config) (*netWriter, error) {
+	if priority < 0 || priority > slog.LOG_LOCAL7|slog.LOG_DEBUG {
+		return nil, errors.New("log/syslog: invalid priority")
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	if formatter == nil {
+		formatter = format.Default
+	}
+	if network == SyslogProtocolTLS {
+		// RFC 5425: octet-counting framing
+		framing = FramingOctetCounting
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+	}
+	hostname := ""
+	if !isUnixProtocol(network) {
+		// like log/syslog, local messages are sent without hostname
+		hostname, _ = os.Hostname()
+	}
+
+	w := &netWriter{
+		priority:  priority,
+		tag:       tag,
+		hostname:  hostname,
+		network:   network,
+		raddr:     raddr,
+		formatter: formatter,
+		framing:   framing,
+		tlsConfig: tlsConfig,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect makes a connection to the syslog server.
+// It must be called with w.mu held.
+func (w *netWriter) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	if w.network == SyslogProtocolTLS {
+		w.conn, err = tls.Dial(SyslogProtocolTCP, w.raddr, w.tlsConfig)
+	} else {
+		w.conn, err = net.Dial(w.network, w.raddr)
+	}
+	if err != nil {
+		return err
+	}
+	if w.hostname == "" && !isUnixProtocol(w.network) {
+		w.hostname = w.conn.LocalAddr().String()
+	}
+	return nil
+}
+
+// CheckConn - checks that stream connection kept between batches is not broken (e.g. closed by server).
+// Datagram connections are not checked
+func (w *netWriter) CheckConn() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return errors.New("syslog: not connected")
+	}
+	if w.network == SyslogProtocolUDP || w.network == SyslogProtocolUnixgram {
+		return nil
+	}
+	return conncheck.ReadProbe(w.conn)
+}
+
+// Close closes a connection to the syslog daemon.
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *netWriter) Write(b []byte) (int, error) {
+	return w.writeAndRetry(&format.Record{Priority: w.priority, Message: string(b)})
+}
+
+func (w *netWriter) Emerg(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_EMERG, Message: m})
+}
+
+func (w *netWriter) Alert(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_ALERT, Message: m})
+}
+
+func (w *netWriter) Crit(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_CRIT, Message: m})
+}
+
+func (w *netWriter) Err(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_ERR, Message: m})
+}
+
+func (w *netWriter) Warning(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_WARNING, Message: m})
+}
+
+func (w *netWriter) Notice(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_NOTICE, Message: m})
+}
+
+func (w *netWriter) Info(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_INFO, Message: m})
+}
+
+func (w *netWriter) Debug(m string) error {
+	return w.WriteRecord(&format.Record{Priority: slog.LOG_DEBUG, Message: m})
+}
+
+// WriteRecord - implements RecordWriter
+func (w *netWriter) WriteRecord(r *format.Record) error {
+	_, err := w.writeAndRetry(r)
+	return err
+}
+
+func (w *netWriter) writeAndRetry(r *format.Record) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rec := *r
+	rec.Priority = (w.priority & facilityMask) | (r.Priority & severityMask)
+	if rec.Tag == "" {
+		rec.Tag = w.tag
+	}
+	if rec.PID == 0 {
+		rec.PID = os.Getpid()
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+
+	if w.conn != nil {
+		if n, err := w.write(&rec); err == nil {
+			return n, err
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	return w.write(&rec)
+}
+
+// write generates and writes a syslog formatted string. For stream
+// transports the message is framed according to w.framing.
+func (w *netWriter) write(r *format.Record) (int, error) {
+	if r.Hostname == "" {
+		r.Hostname = w.hostname
+	}
+	m := w.formatter.Format(r)
+	if w.network != SyslogProtocolUDP && w.network != SyslogProtocolUnixgram {
+		m = frame(m, w.framing)
+	}
+	for i := 0; ; i++ {
+		_, err := w.conn.Write([]byte(m))
+		if err == nil {
+			break
+		}
+		if w.network != SyslogProtocolUnixgram || !isNoBufs(err) || i == noBufsRetries {
+			return 0, err
+		}
+		// receiver queue is full, wait for it instead of reconnecting
+		time.Sleep(noBufsDelay)
+	}
+	return len(r.Message), nil
+}
+
+// frame - returns message framed for stream transport
+func frame(m string, f Framing) string {
+	if f == FramingOctetCounting {
+		return strconv.Itoa(len(m)) + " " + m
+	}
+	if !strings.HasSuffix(m, "\n") {
+		m += "\n"
+	}
+	return m
+}
@@ -0,0 +1,236 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	slog "log/syslog"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"slogger/syslog/format"
+	slRelp "slogger/syslog/relp"
+	"slogger/syslog/relp/relptest"
+	"slogger/syslog/tlsconfig/tlstest"
+)
+
+func TestNetWriter_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			l, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- l
+		}
+	}()
+
+	w, err := dialNet(SyslogProtocolTCP, ln.Addr().String(), slog.LOG_WARNING|slog.LOG_DAEMON, "tag", format.Raw{}, FramingNonTransparent, nil)
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Err("Test message"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+
+	select {
+	case l := <-lines:
+		if l != "Test message\n" {
+			t.Errorf("expect %q, got %q", "Test message\n", l)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("message not received")
+	}
+}
+
+func TestNetWriter_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer pc.Close()
+
+	w, err := dialNet(SyslogProtocolUDP, pc.LocalAddr().String(), slog.LOG_WARNING|slog.LOG_DAEMON, "tag", format.RFC5424{}, FramingNonTransparent, nil)
+	if err != nil {
+		t.Fatalf("cannot dial: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Crit("Test message"); err != nil {
+		t.Errorf("expect no error, got: %v", err)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatalf("message not received: %v", err)
+	}
+	m := string(b[:n])
+	if !strings.HasPrefix(m, "<26>1 ") || !strings.Contains(m, " tag ") || !strings.HasSuffix(m, " - - Test message") {
+		t.Errorf("unexpected message: %q", m)
+	}
+}
+
+func TestSyslog_TLS(t *testing.T) {
+	ca, err := tlstest.NewCA("test CA")
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	serverCert, err := ca.Issue("server", false)
+	if err != nil {
+		t.Fatalf("cannot issue certificate: %v", err)
+	}
+	clientCert, err := ca.Issue("client", true)
+	if err != nil {
+		t.Fatalf("cannot issue certificate: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlstest.ServerConfig(serverCert, ca))
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	srv := &testServer{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go readFrames(conn, FramingOctetCounting, srv.add)
+		}
+	}()
+
+	s, err := New(context.Background(), SyslogProtocolTLS, ln.Addr().String(), "tag", 8, 10*time.Millisecond, 8,
+		WithFormatter(format.Raw{}), WithMultiline(MultilineKeep), WithTLSConfig(&tls.Config{
+			RootCAs:      ca.Pool(),
+			Certificates: []tls.Certificate{clientCert.TLS},
+		}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "first\nline")
+	s.Send(context.Background(), slog.LOG_ERR, "second")
+	s.Close()
+
+	msgs := srv.waitMessages(2)
+	if len(msgs) != 2 || msgs[0] != "first\nline" || msgs[1] != "second" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
+
+func TestNetWriter_TLSUntrusted(t *testing.T) {
+	ca, err := tlstest.NewCA("test CA")
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	serverCert, err := ca.Issue("server", false)
+	if err != nil {
+		t.Fatalf("cannot issue certificate: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlstest.ServerConfig(serverCert, nil))
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	if _, err := dialNet(SyslogProtocolTLS, ln.Addr().String(), slog.LOG_WARNING|slog.LOG_DAEMON, "tag",
+		nil, FramingNonTransparent, &tls.Config{}); err == nil {
+		t.Errorf("expect error for untrusted server certificate, got no error")
+	}
+}
+
+func TestSyslog_RELPTLS(t *testing.T) {
+	ca, err := tlstest.NewCA("test CA")
+	if err != nil {
+		t.Fatalf("cannot create CA: %v", err)
+	}
+	serverCert, err := ca.Issue("server", false)
+	if err != nil {
+		t.Fatalf("cannot issue certificate: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlstest.ServerConfig(serverCert, nil))
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	srv := relptest.Serve(ln)
+	defer srv.Close()
+
+	s, err := New(context.Background(), SyslogProtocolRELPTLS, srv.Addr, "tag", 8, 10*time.Millisecond, 8,
+		WithFormatter(format.Raw{}), WithTLSConfig(&tls.Config{RootCAs: ca.Pool()}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	s.Send(context.Background(), slog.LOG_ERR, "Test message")
+	s.Close()
+
+	if msgs := srv.Messages(); len(msgs) != 1 || msgs[0] != "Test message" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
+
+func TestSyslog_RELPPool(t *testing.T) {
+	var addrs []string
+	var srvs []*relptest.Server
+	for i := 0; i < 2; i++ {
+		srv, err := relptest.NewServer()
+		if err != nil {
+			t.Fatalf("cannot start server: %v", err)
+		}
+		defer srv.Close()
+		srvs = append(srvs, srv)
+		addrs = append(addrs, srv.Addr)
+	}
+
+	s, err := New(context.Background(), SyslogProtocolRELP, addrs[0], "tag", 32, 10*time.Millisecond, 16,
+		WithFormatter(format.Raw{}), WithRELPPool(slRelp.PoolConfig{Addrs: addrs, ConnsPerAddr: 2}))
+	if err != nil {
+		t.Fatalf("cannot create syslog sender: %v", err)
+	}
+	for i := 0; i < 16; i++ {
+		s.Send(context.Background(), slog.LOG_ERR, strconv.Itoa(i))
+	}
+	s.Close()
+
+	total := 0
+	for _, srv := range srvs {
+		n := len(srv.Messages())
+		if n == 0 {
+			t.Errorf("expect messages spread across pool, server %s got no messages", srv.Addr)
+		}
+		total += n
+	}
+	if total != 16 {
+		t.Errorf("expect 16 messages, got: %d", total)
+	}
+}